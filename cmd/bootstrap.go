@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	okta "github.com/HGInsights/gimme-snowflake-creds/pkg/auth"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	bootstrapURL string
+
+	bootstrapCmd = &cobra.Command{
+		Use:   "bootstrap",
+		Args:  cobra.NoArgs,
+		Short: "Create a new profile from an email address",
+		Long:  `Discovers the Okta org for an email address via WebFinger, or reads a team-published bootstrap document, and pre-fills a new profile`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// The profile doesn't exist yet, so there is nothing to load or validate
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			v, home := loadConfig()
+			c.HomeDir = home
+			config.LoadDefaults(&c)
+
+			// Prompt for anything required that wasn't passed
+			if c.Profile.Username == "" {
+				c.Profile.Username = promptValue("Okta username (email)")
+			}
+			if c.ProfileName == "" {
+				c.ProfileName = promptValue("Profile name")
+			}
+
+			if v.IsSet(c.ProfileName) {
+				fmt.Println(string(c.ColorFailure), "Profile", c.ProfileName, "already exists!")
+				os.Exit(0)
+			}
+
+			settings := okta.Bootstrap{
+				OktaOrg:     c.Profile.OktaOrg,
+				IssuerURL:   c.Profile.IssuerURL,
+				ClientID:    c.Profile.ClientID,
+				RedirectURI: c.Profile.RedirectURI,
+			}
+
+			// Team-published settings fill in anything not passed as a flag
+			if bootstrapURL != "" {
+				published, err := okta.FetchBootstrap(c, bootstrapURL)
+				if err != nil {
+					fmt.Println(string(c.ColorFailure), "Unable to read bootstrap document!")
+					c.Logger.Debug("Unable to read bootstrap document", "error", err)
+					os.Exit(0)
+				}
+
+				settings.OktaOrg = firstNonEmpty(settings.OktaOrg, published.OktaOrg)
+				settings.IssuerURL = firstNonEmpty(settings.IssuerURL, published.IssuerURL)
+				settings.ClientID = firstNonEmpty(settings.ClientID, published.ClientID)
+				settings.RedirectURI = firstNonEmpty(settings.RedirectURI, published.RedirectURI)
+			}
+
+			if settings.OktaOrg == "" {
+				org, err := okta.Discover(c, c.Profile.Username)
+				if err != nil {
+					fmt.Println(string(c.ColorFailure), "Unable to discover the Okta org, pass it with --okta-org")
+					c.Logger.Debug("Unable to discover Okta org", "error", err)
+					os.Exit(0)
+				}
+
+				fmt.Println(string(c.ColorSuccess), "Discovered Okta org:", org)
+				settings.OktaOrg = org
+			}
+
+			if settings.IssuerURL == "" {
+				settings.IssuerURL = settings.OktaOrg + "/oauth2/default"
+				fmt.Println(string(c.ColorSuccess), "Assuming the default authorization server:", settings.IssuerURL)
+			}
+
+			profile := yaml.MapSlice{
+				{Key: "username", Value: c.Profile.Username},
+				{Key: "okta-org", Value: settings.OktaOrg},
+				{Key: "issuer-url", Value: settings.IssuerURL},
+			}
+			if settings.ClientID != "" {
+				profile = append(profile, yaml.MapItem{Key: "client-id", Value: settings.ClientID})
+			}
+			if settings.RedirectURI != "" {
+				profile = append(profile, yaml.MapItem{Key: "redirect-uri", Value: settings.RedirectURI})
+			}
+
+			// The profile is new, so it's appended and the rest of the file is left as it is
+			configFile := home + "/.okta_snowflake_login_config"
+			out, err := yaml.Marshal(yaml.MapSlice{{Key: c.ProfileName, Value: profile}})
+			if err == nil {
+				err = appendConfig(configFile, out)
+			}
+			if err != nil {
+				fmt.Println(string(c.ColorFailure), "Couldn't write configuration!")
+				c.Logger.Debug("Couldn't write configuration", "error", err)
+				os.Exit(0)
+			}
+
+			fmt.Println(string(c.ColorSuccess), "Profile", c.ProfileName, "written to:", configFile)
+			fmt.Println(string(c.ColorSuccess), "Complete the profile with your Snowflake account, database, warehouse, role and odbc-path")
+		},
+	}
+)
+
+func init() {
+	bootstrapCmd.Flags().StringVar(&bootstrapURL, "bootstrap-url", "", "URL of a team-published JSON document with issuer, client-id and redirect-uri")
+
+	rootCmd.AddCommand(bootstrapCmd)
+}
+
+// appendConfig adds YAML to the end of the config file, creating it if needed
+func appendConfig(path string, out []byte) error {
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		out = append([]byte("\n"), out...)
+	}
+
+	_, err = f.Write(out)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func promptValue(label string) string {
+	prompt := promptui.Prompt{
+		Label: label,
+		Validate: func(input string) error {
+			if len(input) == 0 {
+				return errors.New("value must not be empty")
+			}
+			return nil
+		},
+	}
+
+	result, err := prompt.Run()
+	if err != nil {
+		c.Logger.Debug("Prompt failed", "error", err)
+		os.Exit(0)
+	}
+
+	return result
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/generator"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec -- command [args...]",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Run a command with the profile's credentials in its environment",
+	Long: `Runs a command with the environment variables of the profile's selected outputs (such as terraform, sqlalchemy, airflow and schemachange), using the token from its token cache. Every such output is used when the profile selects none:
+
+  gimme-snowflake-creds exec -p prod --output terraform -- terraform apply`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Profiles without OAuth authenticate in the browser and have no token
+		token, err := generator.ReadTokenCache(c)
+		if c.Profile.OAuth && (err != nil || token.AccessToken == "") {
+			fmt.Fprintln(os.Stderr, string(c.ColorFailure), "No token for profile", c.ProfileName+": run gimme-snowflake-creds first")
+			os.Exit(1)
+		}
+
+		// Messages go to stderr so that stdout only ever holds the command's output
+		if !token.ExpiresAt.IsZero() && time.Now().After(token.ExpiresAt) {
+			fmt.Fprintln(os.Stderr, string(c.ColorFailure), "Token for profile", c.ProfileName, "expired at", token.ExpiresAt.Format(time.RFC3339))
+		}
+
+		writers := []generator.EnvWriter{}
+		for _, w := range generator.EnvWriters() {
+			if w.Enabled(c.Profile) {
+				writers = append(writers, w)
+			}
+		}
+		if len(writers) == 0 {
+			writers = generator.EnvWriters()
+		}
+
+		// The vault's key and passphrase stay with gimme-snowflake-creds
+		env := vault.Environ()
+		for _, w := range writers {
+			for _, v := range w.Env(c, token) {
+				env = append(env, v[0]+"="+v[1])
+			}
+		}
+
+		command := exec.CommandContext(cmd.Context(), args[0], args[1:]...)
+		command.Env = env
+		command.Stdin = os.Stdin
+		command.Stdout = os.Stdout
+		command.Stderr = os.Stderr
+
+		err = command.Run()
+
+		// Pass the command's exit code through
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		} else if err != nil {
+			fmt.Fprintln(os.Stderr, string(c.ColorFailure), "Unable to run", args[0]+":", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	// Flags after the command belong to it
+	execCmd.Flags().SetInterspersed(false)
+
+	rootCmd.AddCommand(execCmd)
+}
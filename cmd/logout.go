@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+
+	okta "github.com/HGInsights/gimme-snowflake-creds/pkg/auth"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/generator"
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Args:  cobra.NoArgs,
+	Short: "Revoke tokens and remove them from generated configuration",
+	Long:  `Revokes the profile's OAuth tokens, ends the Okta session and removes tokens from every generated configuration`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Gather previously issued tokens
+		token, err := generator.ReadTokenCache(c)
+		if err != nil {
+			c.Logger.Debug("Unable to read token cache", "error", err)
+		}
+
+		// Tokens written by earlier releases are only in the outputs
+		generic, err := generator.ReadGenericCredentials(c)
+		if err == nil {
+			token.AccessToken = firstNonEmpty(token.AccessToken, generic.AccessToken)
+			token.RefreshToken = firstNonEmpty(token.RefreshToken, generic.RefreshToken)
+			token.SessionID = firstNonEmpty(token.SessionID, generic.SessionID)
+		}
+		if token.AccessToken == "" {
+			token.AccessToken = generator.ReadODBCToken(c)
+		}
+
+		// Revoke tokens and end the Okta session
+		if c.Profile.OAuth {
+			err = okta.Logout(c, token)
+			if err != nil {
+				fmt.Println(string(c.ColorFailure), "Logout:", err)
+				c.Logger.Debug("Unable to revoke tokens", "error", err)
+			}
+		}
+
+		// Remove tokens from the cache and every output
+		err = generator.RemoveTokenCache(c)
+		if err != nil {
+			c.Logger.Debug("Unable to remove token cache", "error", err)
+		}
+
+		for _, w := range generator.Writers() {
+			err = w.Remove(cmd.Context(), c)
+			if err != nil {
+				c.Logger.Debug("Unable to remove token from "+w.Name()+" output", err)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+}
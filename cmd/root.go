@@ -1,16 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
 	"syscall"
 
 	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
 	okta "github.com/HGInsights/gimme-snowflake-creds/pkg/auth"
 	"github.com/HGInsights/gimme-snowflake-creds/pkg/generator"
 	"github.com/HGInsights/gimme-snowflake-creds/pkg/utils"
-	"github.com/hashicorp/go-hclog"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -23,6 +25,9 @@ var (
 	// Initialize configuration
 	c config.Configuration
 
+	// Outputs selected for a single run, overriding the profile
+	outputs []string
+
 	rootCmd = &cobra.Command{
 		Use:   "gimme-snowflake-creds",
 		Args:  cobra.NoArgs,
@@ -33,40 +38,49 @@ var (
 			return initConfig(cmd)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
+			// Only the generic output can hold an encrypted token; other tools need it in plaintext
+			if c.Profile.EncryptToken && c.Profile.OAuth {
+				if plaintext := generator.PlaintextOutputs(c.Profile); len(plaintext) > 0 {
+					fmt.Println(string(c.ColorFailure), "encrypt-token only applies to the generic output, but", strings.Join(plaintext, ", "), "would write the token in plaintext: select outputs: [generic]")
+					os.Exit(0)
+				}
+			}
+
 			// Initialize authentication flow
 			token, err := okta.Auth(c)
 			if err != nil {
 				c.Logger.Debug("Unable to initiate the authentication flow", err)
 			}
 
-			// Write generic configuration
-			if c.Profile.Generic {
-				err = generator.WriteGenericCredentials(c, token)
+			// Cache the tokens for `exec`, `token` and `logout`, whichever outputs are selected
+			if c.Profile.OAuth {
+				err = generator.WriteTokenCache(c, token)
 				if err != nil {
-					c.Logger.Debug("Unable to write generic configuration file", err)
+					fmt.Println(string(c.ColorFailure), "Couldn't cache token!")
+					c.Logger.Debug("Unable to write token cache", "error", err)
 				}
 			}
 
-			// Write ODBC configuration
-			err = generator.WriteODBCConfig(c, token)
-			if err != nil {
-				c.Logger.Debug("Unable to write ODBC configuration file", err)
-			}
+			// Write every enabled output
+			for _, w := range generator.Writers() {
+				if !w.Enabled(c.Profile) {
+					continue
+				}
 
-			// Write DBT configuration
-			err = generator.WriteDBTConfig(c, token)
-			if err != nil {
-				c.Logger.Debug("Unable to write DBT configuration file", err)
+				err = w.Write(cmd.Context(), c, token)
+				if err != nil {
+					c.Logger.Debug("Unable to write "+w.Name()+" output", err)
+				}
 			}
 		},
 	}
 )
 
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	cobra.CheckErr(rootCmd.ExecuteContext(context.Background()))
 
 	// CTRL+C catcher
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
@@ -76,80 +90,41 @@ func Execute() {
 
 func init() {
 	// Set flags
-	rootCmd.Flags().StringVarP(&c.ProfileName, "profile", "p", "", "profile selection")
-	rootCmd.Flags().BoolVarP(&c.Forget, "forget", "f", false, "forget saved credentials")
-	rootCmd.Flags().StringVarP(&c.Profile.Account, "account", "a", "", "Snowflake account, like: xy12345.us-east-1")
-	rootCmd.Flags().StringVarP(&c.ODBCDriverName, "driver-name", "z", "", "ODBC driver name")
-	rootCmd.Flags().StringVarP(&c.ODBCDriverPath, "driver-path", "v", "", "ODBC driver path (local)")
-	rootCmd.Flags().StringVarP(&c.Profile.Database, "database", "d", "", "Snowflake database")
-	rootCmd.Flags().StringVarP(&c.Profile.Warehouse, "warehouse", "w", "", "Snowflake warehouse")
-	rootCmd.Flags().StringVarP(&c.Profile.Schema, "schema", "x", "PUBLIC", "Snowflake schema")
-	rootCmd.Flags().StringVar(&c.Profile.DbtProfile, "dbt-profile", "default", "What dbt profile to write to")
-	rootCmd.Flags().Uint64VarP(&c.Profile.ThreadCount, "threads", "t", 10, "The number of concurrent models dbt should build.")
-	rootCmd.Flags().BoolVarP(&c.Profile.OAuth, "oauth", "", true, "enable/disable credential retrieval")
-	rootCmd.Flags().BoolVarP(&c.Profile.Generic, "generic", "", true, "enable/disable generic credential setup")
-	rootCmd.Flags().BoolVar(&c.Profile.KeepAlive, "keep-alive", true, "the snowflake client will keep connections for longer than the default 4 hours.")
-	rootCmd.Flags().StringVarP(&c.Profile.OktaOrg, "okta-org", "o", "", "like: https://funtimes.oktapreview.com")
-	rootCmd.Flags().StringVarP(&c.Profile.ODBCPath, "odbc-path", "n", "/etc", "Path containing odbc.ini")
-	rootCmd.Flags().StringVarP(&c.Profile.ClientID, "client-id", "c", "", "OIDC Client ID of Okta application")
-	rootCmd.Flags().StringVarP(&c.Profile.Role, "role", "s", "", "Snowflake role name")
-	rootCmd.Flags().StringVarP(&c.Profile.IssuerURL, "issuer-url", "i", "", "issuer URL of Okta authorization server")
-	rootCmd.Flags().StringVarP(&c.Profile.RedirectURI, "redirect-uri", "r", "", "redirect URI of Okta application")
-	rootCmd.Flags().StringVarP(&c.Profile.Username, "username", "u", "", "username for Okta")
+	rootCmd.PersistentFlags().StringVarP(&c.ProfileName, "profile", "p", "", "profile selection")
+	rootCmd.PersistentFlags().BoolVarP(&c.Forget, "forget", "f", false, "forget saved credentials")
+	rootCmd.PersistentFlags().BoolVar(&c.PasswordStdin, "password-stdin", false, "read the Okta password from stdin")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.Account, "account", "a", "", "Snowflake account, like: xy12345.us-east-1")
+	rootCmd.PersistentFlags().StringVarP(&c.ODBCDriverName, "driver-name", "z", "", "ODBC driver name")
+	rootCmd.PersistentFlags().StringVarP(&c.ODBCDriverPath, "driver-path", "v", "", "ODBC driver path (local)")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.Database, "database", "d", "", "Snowflake database")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.Warehouse, "warehouse", "w", "", "Snowflake warehouse")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.Schema, "schema", "x", "PUBLIC", "Snowflake schema")
+	rootCmd.PersistentFlags().StringVar(&c.Profile.DbtProfile, "dbt-profile", "default", "What dbt profile to write to")
+	rootCmd.PersistentFlags().Uint64VarP(&c.Profile.ThreadCount, "threads", "t", 10, "The number of concurrent models dbt should build.")
+	rootCmd.PersistentFlags().BoolVarP(&c.Profile.OAuth, "oauth", "", true, "enable/disable credential retrieval")
+	rootCmd.PersistentFlags().BoolVarP(&c.Profile.Generic, "generic", "", true, "enable/disable generic credential setup")
+	rootCmd.PersistentFlags().StringSliceVar(&outputs, "output", nil, "outputs to generate for this run, like: dbt,odbc")
+	rootCmd.PersistentFlags().BoolVar(&c.Profile.EncryptToken, "encrypt-token", false, "encrypt tokens in generic credentials with the vault key")
+	rootCmd.PersistentFlags().BoolVar(&c.Profile.KeepAlive, "keep-alive", true, "the snowflake client will keep connections for longer than the default 4 hours.")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.OktaOrg, "okta-org", "o", "", "like: https://funtimes.oktapreview.com")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.ODBCPath, "odbc-path", "n", "/etc", "Path containing odbc.ini")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.ClientID, "client-id", "c", "", "OIDC Client ID of Okta application")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.Role, "role", "s", "", "Snowflake role name")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.IssuerURL, "issuer-url", "i", "", "issuer URL of Okta authorization server")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.RedirectURI, "redirect-uri", "r", "", "redirect URI of Okta application")
+	rootCmd.PersistentFlags().StringVarP(&c.Profile.Username, "username", "u", "", "username for Okta")
+	rootCmd.PersistentFlags().StringVar(&c.Profile.SecretStore, "secret-store", "keyring", "where to keep the Okta password: keyring, file, pass, env or command")
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig(cmd *cobra.Command) error {
-	// Find home directory.
-	home, err := homedir.Dir()
-	cobra.CheckErr(err)
-
-	// Initialize Viper
-	v := viper.New()
-
-	// Search config in home directory with name ".okta_snowflake_login_config" (without extension).
-	v.AddConfigPath(home)
-	v.SetConfigType("yaml")
-	v.SetConfigName(".okta_snowflake_login_config")
-
-	v.SetEnvPrefix("GSC") // Set environment variable prefix
-	v.AutomaticEnv()      // Read in environment variables that match
-
-	// Configure logging
-	logLevel := v.GetString("LOG")
-	if logLevel == "" {
-		logLevel = "INFO"
-	}
-	log := hclog.New(&hclog.LoggerOptions{
-		Level: hclog.LevelFromString(logLevel),
-	})
-	c.Logger = log
-
-	// Read in configuration
-	if err := v.ReadInConfig(); err == nil {
-		c.Logger.Debug(v.ConfigFileUsed())
-	}
-
-	// Unmarshal configuration into configuration struct
-	err = v.Unmarshal(&c)
-	if err != nil {
-		c.Logger.Error("error", err)
-		os.Exit(0)
-	}
+	v, home := loadConfig()
 
 	// Provide list of profiles if no profile argument is passed
 	if c.ProfileName == "" {
-		profiles := []string{}
-
-		for key := range v.AllSettings() {
-			if !utils.Contains(config.GlobalParams, key) {
-				profiles = append(profiles, key)
-			}
-		}
-
 		prompt := promptui.Select{
 			Label: "Select a profile",
-			Items: profiles,
+			Items: profileNames(v),
 		}
 
 		_, profile, err := prompt.Run()
@@ -161,26 +136,32 @@ func initConfig(cmd *cobra.Command) error {
 		c.ProfileName = profile
 	}
 
-	// Unmarshal profile into profile struct
-	err = v.UnmarshalKey(c.ProfileName, &c.Profile)
+	// Load the profile, shared with Go programs using pkg/gosnowflake
+	err := config.LoadProfile(v, &c, home, c.ProfileName)
 	if err != nil {
-		c.Logger.Error("error", err)
+		fmt.Println(string(c.ColorFailure), err)
 		os.Exit(0)
 	}
 
-	// Try to determine what the default
-	c.DefaultProfile = v.GetString("default")
-	if c.DefaultProfile == "" {
-		c.DefaultProfile = v.GetString("default." + c.Profile.DbtProfile)
+	// Select outputs, which must be registered
+	if cmd.Flags().Changed("output") {
+		c.Profile.Outputs = outputs
+	}
+	for _, output := range c.Profile.Outputs {
+		if _, ok := generator.Lookup(output); !ok {
+			fmt.Println(string(c.ColorFailure), "Unknown output", output)
+			os.Exit(0)
+		}
 	}
-
-	// Load default parameters
-	c.HomeDir = home
-	config.LoadDefaults(&c)
 
 	// Validate configuration
 	err = config.ValidateConfiguration(&c)
 	if err != nil {
+		if missing, ok := err.(config.MissingParametersError); ok {
+			for _, parameter := range missing {
+				fmt.Println(string(c.ColorFailure), "Parameter", parameter, "is required")
+			}
+		}
 		c.Logger.Debug("error", err)
 		os.Exit(0)
 	}
@@ -191,6 +172,36 @@ func initConfig(cmd *cobra.Command) error {
 	return nil
 }
 
+// loadConfig configures logging and reads in the config file, returning it along with the home directory.
+func loadConfig() (*viper.Viper, string) {
+	// Find home directory.
+	home, err := homedir.Dir()
+	cobra.CheckErr(err)
+
+	// Read in configuration
+	v, log, err := config.Read(home)
+	c.Logger = log
+	if err == nil {
+		c.Logger.Debug(v.ConfigFileUsed())
+	}
+
+	return v, home
+}
+
+// profileNames lists the profiles in the config file.
+func profileNames(v *viper.Viper) []string {
+	profiles := []string{}
+
+	for key := range v.AllSettings() {
+		if !utils.Contains(config.GlobalParams, key) {
+			profiles = append(profiles, key)
+		}
+	}
+	sort.Strings(profiles)
+
+	return profiles
+}
+
 func bindFlags(cmd *cobra.Command, v *viper.Viper) {
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
 		v.BindPFlag(f.Name, f)
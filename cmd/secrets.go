@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/secrets"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	purgeAll bool
+
+	secretsCmd = &cobra.Command{
+		Use:   "secrets",
+		Short: "List and purge stored secrets",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Secrets span every profile, so none is selected or validated
+			return nil
+		},
+	}
+
+	secretsListCmd = &cobra.Command{
+		Use:   "list",
+		Args:  cobra.NoArgs,
+		Short: "Show which profiles have stored secrets",
+		Run: func(cmd *cobra.Command, args []string) {
+			v := loadSecretsConfig()
+
+			for _, name := range profileNames(v) {
+				p, err := loadProfile(v, name)
+				if err != nil {
+					continue
+				}
+
+				store, err := secrets.New(p)
+				if err != nil {
+					fmt.Println(string(c.ColorFailure), name+":", err)
+					continue
+				}
+
+				// Read-only stores may run commands or read the environment, so they aren't queried
+				if !store.Writable() {
+					fmt.Println(string(c.ColorSuccess), name+":", "managed by", store.Name())
+					continue
+				}
+
+				stored := []string{}
+				if _, err := store.Get(secrets.Key(p)); err == nil {
+					stored = append(stored, "password")
+				}
+				if _, err := store.Get(p.Profile.Username); err == nil && secrets.Key(p) != p.Profile.Username {
+					stored = append(stored, "password (not yet migrated)")
+				}
+				if _, err := store.Get(secrets.TokenKey(p, secrets.RefreshToken)); err == nil {
+					stored = append(stored, "refresh token")
+				}
+				if _, err := store.Get(secrets.TokenKey(p, secrets.SessionID)); err == nil {
+					stored = append(stored, "Okta session")
+				}
+				if p.Profile.SecretStore == "file" {
+					if _, err := vault.Open(p).Get(vault.TOTPSeeds, secrets.Key(p)); err == nil {
+						stored = append(stored, "TOTP seed")
+					}
+				}
+
+				if len(stored) == 0 {
+					fmt.Println(string(c.ColorFailure), name+":", "nothing stored in", store.Name())
+				} else {
+					fmt.Println(string(c.ColorSuccess), name+":", stored, "in", store.Name(), "as", secrets.Key(p))
+				}
+			}
+		},
+	}
+
+	secretsPurgeCmd = &cobra.Command{
+		Use:   "purge",
+		Args:  cobra.NoArgs,
+		Short: "Remove stored secrets for a profile, or every profile with --all",
+		Run: func(cmd *cobra.Command, args []string) {
+			v := loadSecretsConfig()
+
+			if c.ProfileName == "" && !purgeAll {
+				fmt.Println(string(c.ColorFailure), "Pass a profile with --profile, or --all to purge every profile")
+				os.Exit(0)
+			}
+
+			profiles := []string{c.ProfileName}
+			if purgeAll {
+				profiles = profileNames(v)
+			}
+
+			for _, name := range profiles {
+				p, err := loadProfile(v, name)
+				if err != nil {
+					fmt.Println(string(c.ColorFailure), "Profile", name, "not found!")
+					continue
+				}
+
+				purgeProfile(p)
+			}
+		},
+	}
+)
+
+func init() {
+	secretsPurgeCmd.Flags().BoolVar(&purgeAll, "all", false, "purge secrets of every profile")
+
+	secretsCmd.AddCommand(secretsListCmd)
+	secretsCmd.AddCommand(secretsPurgeCmd)
+
+	rootCmd.AddCommand(secretsCmd)
+}
+
+func loadSecretsConfig() *viper.Viper {
+	v, home := loadConfig()
+	c.HomeDir = home
+	config.LoadDefaults(&c)
+
+	return v
+}
+
+// loadProfile returns a copy of the configuration with the named profile loaded
+func loadProfile(v *viper.Viper, name string) (config.Configuration, error) {
+	p := c
+	p.ProfileName = name
+	p.Profile = config.Profile{}
+
+	if !v.IsSet(name) {
+		return p, errors.New("profile not found")
+	}
+
+	err := v.UnmarshalKey(name, &p.Profile)
+	if err != nil {
+		c.Logger.Debug("Unable to unmarshal profile", "profile", name, "error", err)
+		return p, err
+	}
+
+	return p, nil
+}
+
+func purgeProfile(p config.Configuration) {
+	store, err := secrets.New(p)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), p.ProfileName+":", err)
+		return
+	}
+	if !store.Writable() {
+		fmt.Println(string(c.ColorSuccess), p.ProfileName+":", "managed by", store.Name(), "- nothing to purge")
+		return
+	}
+
+	purge := func(what string, err error) {
+		if err == nil {
+			fmt.Println(string(c.ColorSuccess), p.ProfileName+":", what, "removed from", store.Name())
+		} else if !errors.Is(err, secrets.ErrNotFound) && !errors.Is(err, vault.ErrNotFound) {
+			fmt.Println(string(c.ColorFailure), p.ProfileName+":", "unable to remove", what+":", err)
+		}
+	}
+
+	purge("password", store.Delete(secrets.Key(p)))
+	if secrets.Key(p) != p.Profile.Username {
+		purge("unmigrated password", store.Delete(p.Profile.Username))
+	}
+	purge("refresh token", store.Delete(secrets.TokenKey(p, secrets.RefreshToken)))
+	purge("Okta session", store.Delete(secrets.TokenKey(p, secrets.SessionID)))
+	if p.Profile.SecretStore == "file" {
+		purge("TOTP seed", vault.Open(p).Delete(vault.TOTPSeeds, secrets.Key(p)))
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/generator"
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Args:  cobra.NoArgs,
+	Short: "Print the profile's access token",
+	Long: `Prints the profile's access token from its token cache, decrypting it if it's encrypted at rest, so tools can fetch it at use time:
+
+  SNOWFLAKE_TOKEN="$(gimme-snowflake-creds token -p prod)"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		token, err := generator.ReadTokenCache(c)
+		if err != nil || token.AccessToken == "" {
+			fmt.Fprintln(os.Stderr, string(c.ColorFailure), "No token for profile", c.ProfileName+": run gimme-snowflake-creds first")
+			os.Exit(1)
+		}
+
+		// Messages go to stderr so that stdout only ever holds the token
+		if !token.ExpiresAt.IsZero() && time.Now().After(token.ExpiresAt) {
+			fmt.Fprintln(os.Stderr, string(c.ColorFailure), "Token for profile", c.ProfileName, "expired at", token.ExpiresAt.Format(time.RFC3339))
+		}
+
+		fmt.Println(token.AccessToken)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tokenCmd)
+}
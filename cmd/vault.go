@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/secrets"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	vaultTTL time.Duration
+
+	vaultCmd = &cobra.Command{
+		Use:   "vault",
+		Short: "Manage the encrypted credential vault",
+		Long:  `Manages ~/.gsc/vault, the encrypted store used by profiles with "secret-store: file"`,
+	}
+
+	vaultUnlockCmd = &cobra.Command{
+		Use:   "unlock",
+		Args:  cobra.NoArgs,
+		Short: "Unlock the vault for the rest of the shell session",
+		Long: `Derives the vault key once and starts a session that expires after --ttl, printed as an environment variable so that later runs don't ask for the passphrase:
+
+  eval "$(gimme-snowflake-creds vault unlock)"`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Unlocking doesn't depend on any profile
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			_, home := loadConfig()
+			c.HomeDir = home
+			config.LoadDefaults(&c)
+
+			// Unlocking again replaces the current session, even a broken one
+			os.Unsetenv(vault.KeyEnv)
+
+			session, err := vault.Open(c).Unlock(vaultTTL)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, string(c.ColorFailure), "Unable to unlock vault:", err)
+				os.Exit(0)
+			}
+
+			fmt.Printf("export %v=%v\n", vault.KeyEnv, session)
+		},
+	}
+
+	vaultLockCmd = &cobra.Command{
+		Use:   "lock",
+		Args:  cobra.NoArgs,
+		Short: "End the shell session's vault session",
+		Long: `Ends the session started by "vault unlock", so that its key stops working everywhere it was passed to:
+
+  eval "$(gimme-snowflake-creds vault lock)"`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			_, home := loadConfig()
+			c.HomeDir = home
+			config.LoadDefaults(&c)
+
+			if session := os.Getenv(vault.KeyEnv); session != "" {
+				_, err := vault.Open(c).Lock(session)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, string(c.ColorFailure), "Unable to lock vault:", err)
+					os.Exit(0)
+				}
+			}
+
+			fmt.Printf("unset %v\n", vault.KeyEnv)
+		},
+	}
+
+	vaultTOTPCmd = &cobra.Command{
+		Use:   "totp",
+		Args:  cobra.NoArgs,
+		Short: "Save the profile user's TOTP seed in the vault",
+		Run: func(cmd *cobra.Command, args []string) {
+			prompt := promptui.Prompt{
+				Label: "TOTP seed (base32) for " + c.Profile.Username,
+				Validate: func(input string) error {
+					if len(input) == 0 {
+						return errors.New("seed must not be empty")
+					}
+					return nil
+				},
+				Mask: '*',
+			}
+
+			seed, err := prompt.Run()
+			if err != nil {
+				c.Logger.Debug("Prompt failed", "error", err)
+				os.Exit(0)
+			}
+
+			err = vault.Open(c).Set(vault.TOTPSeeds, secrets.Key(c), seed)
+			if err != nil {
+				fmt.Println(string(c.ColorFailure), "Unable to save TOTP seed:", err)
+				os.Exit(0)
+			}
+
+			fmt.Println(string(c.ColorSuccess), "TOTP seed saved to vault")
+		},
+	}
+)
+
+func init() {
+	vaultUnlockCmd.Flags().DurationVar(&vaultTTL, "ttl", vault.DefaultSessionTTL, "how long the vault stays unlocked")
+
+	vaultCmd.AddCommand(vaultUnlockCmd)
+	vaultCmd.AddCommand(vaultLockCmd)
+	vaultCmd.AddCommand(vaultTOTPCmd)
+
+	rootCmd.AddCommand(vaultCmd)
+}
@@ -1,9 +1,10 @@
 package config
 
 import (
-	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/HGInsights/gimme-snowflake-creds/pkg/utils"
 	"github.com/go-playground/validator"
@@ -26,6 +27,12 @@ var (
 		"redirect-uri",
 	}
 
+	odbcParams = []string{
+		"driver-name",
+		"driver-path",
+		"odbc-path",
+	}
+
 	colorGreen = "\033[32m"
 	colorRed   = "\033[31m"
 )
@@ -37,6 +44,7 @@ type Configuration struct {
 	ProfileName    string
 	Profile        Profile
 	Forget         bool
+	PasswordStdin  bool
 	HomeDir        string
 	Logger         hclog.Logger
 	ColorSuccess   string
@@ -44,28 +52,45 @@ type Configuration struct {
 }
 
 type Profile struct {
-	OAuth       bool   `mapstructure:"oauth"`
-	Generic     bool   `mapstructure:"generic"`
-	Account     string `mapstructure:"account" validate:"required"`
-	Database    string `mapstructure:"database" validate:"required"`
-	Warehouse   string `mapstructure:"warehouse" validate:"required"`
-	Schema      string `mapstructure:"schema"`
-	DbtProfile  string `mapstructure:"dbt-profile"`
-	ThreadCount uint64 `mapstructure:"threads"`
-	KeepAlive   bool   `mapstructure:"client_session_keep_alive"`
-	OktaOrg     string `mapstructure:"okta-org" validate:"required,url"`
-	ODBCPath    string `mapstructure:"odbc-path" validate:"required"`
-	ClientID    string `mapstructure:"client-id" validate:"required"`
-	Role        string `mapstructure:"role" validate:"required"`
-	IssuerURL   string `mapstructure:"issuer-url" validate:"required,url"`
-	RedirectURI string `mapstructure:"redirect-uri" validate:"required,uri"`
-	Username    string `mapstructure:"username" validate:"required,email"`
-	Password    string
+	OAuth           bool   `mapstructure:"oauth"`
+	Generic         bool   `mapstructure:"generic"`
+	Account         string `mapstructure:"account" validate:"required"`
+	Database        string `mapstructure:"database" validate:"required"`
+	Warehouse       string `mapstructure:"warehouse" validate:"required"`
+	Schema          string `mapstructure:"schema"`
+	DbtProfile      string `mapstructure:"dbt-profile"`
+	ThreadCount     uint64 `mapstructure:"threads"`
+	KeepAlive       bool   `mapstructure:"client_session_keep_alive"`
+	OktaOrg         string `mapstructure:"okta-org" validate:"required,url"`
+	ODBCPath        string `mapstructure:"odbc-path" validate:"required"`
+	ClientID        string `mapstructure:"client-id" validate:"required"`
+	Role            string `mapstructure:"role" validate:"required"`
+	IssuerURL       string `mapstructure:"issuer-url" validate:"required,url"`
+	RedirectURI     string `mapstructure:"redirect-uri" validate:"required,uri"`
+	Username        string `mapstructure:"username" validate:"required,email"`
+	Password        string
+	SecretStore     string                       `mapstructure:"secret-store" validate:"omitempty,oneof=keyring file pass env command"`
+	PasswordEnv     string                       `mapstructure:"password-env"`
+	PasswordCommand string                       `mapstructure:"password-command"`
+	EncryptToken    bool                         `mapstructure:"encrypt-token"`
+	RefreshTokens   bool                         `mapstructure:"refresh-tokens"`
+	Outputs         []string                     `mapstructure:"outputs"`
+	OutputOptions   map[string]map[string]string `mapstructure:"output-options"`
+}
+
+// MissingParametersError lists the required parameters a configuration is missing
+type MissingParametersError []string
+
+func (e MissingParametersError) Error() string {
+	return "missing required parameters: " + strings.Join(e, ", ")
 }
 
 type Credentials struct {
-	ExpiresIn   int
-	AccessToken string
+	ExpiresIn    int
+	ExpiresAt    time.Time
+	AccessToken  string
+	RefreshToken string
+	SessionID    string
 }
 
 func LoadDefaults(c *Configuration) error {
@@ -95,16 +120,53 @@ func ValidateConfiguration(c *Configuration) error {
 
 	err := validate.Struct(c)
 	if err != nil {
+		missing := MissingParametersError{}
+
 		for _, err := range err.(validator.ValidationErrors) {
 			if !c.Profile.OAuth && utils.Contains(oauthParams, err.Field()) {
-				return nil
+				continue
+			} else if !c.Profile.OutputEnabled("odbc", true) && utils.Contains(odbcParams, err.Field()) {
+				continue
 			} else {
-				fmt.Println(string(c.ColorFailure), "Parameter", err.Field(), "is required")
+				missing = append(missing, err.Field())
 			}
 		}
 
-		return err
+		if len(missing) > 0 {
+			return missing
+		}
 	}
 
 	return nil
 }
+
+// OutputEnabled reports whether an output is selected by the profile's `outputs` list,
+// falling back to the output's default when the profile doesn't list any
+func (p Profile) OutputEnabled(output string, fallback bool) bool {
+	if len(p.Outputs) == 0 {
+		return fallback
+	}
+
+	return utils.Contains(p.Outputs, output)
+}
+
+// OutputOption returns an option of an output from the profile's `output-options`,
+// or the fallback when it isn't set
+func (p Profile) OutputOption(output string, option string, fallback string) string {
+	if value, ok := p.OutputOptions[output][option]; ok && value != "" {
+		return value
+	}
+
+	return fallback
+}
+
+// OutputFlag returns a boolean option of an output from the profile's `output-options`,
+// or the fallback when it isn't set
+func (p Profile) OutputFlag(output string, option string, fallback bool) bool {
+	value, err := strconv.ParseBool(p.OutputOption(output, option, ""))
+	if err != nil {
+		return fallback
+	}
+
+	return value
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/logging"
+	"github.com/hashicorp/go-hclog"
+	"github.com/spf13/viper"
+)
+
+// Read reads `.okta_snowflake_login_config` from the home directory, along with GSC_*
+// environment variables, and returns it with a logger at the level set by GSC_LOG. Both
+// are returned even when the file can't be read
+func Read(home string) (*viper.Viper, hclog.Logger, error) {
+	v := viper.New()
+
+	// Search config in home directory with name ".okta_snowflake_login_config" (without extension).
+	v.AddConfigPath(home)
+	v.SetConfigType("yaml")
+	v.SetConfigName(".okta_snowflake_login_config")
+
+	v.SetEnvPrefix("GSC") // Set environment variable prefix
+	v.AutomaticEnv()      // Read in environment variables that match
+
+	// Configure logging
+	logLevel := v.GetString("LOG")
+	if logLevel == "" {
+		logLevel = "INFO"
+	}
+	logger := hclog.New(&hclog.LoggerOptions{
+		Level:  hclog.LevelFromString(logLevel),
+		Output: logging.NewRedactingWriter(os.Stderr),
+	})
+
+	return v, logger, v.ReadInConfig()
+}
+
+// LoadProfile unmarshals the global settings and a profile into the configuration, works
+// out the default profile and loads defaults. The result still needs ValidateConfiguration
+func LoadProfile(v *viper.Viper, c *Configuration, home string, profile string) error {
+	// Unmarshal configuration into configuration struct
+	err := v.Unmarshal(c)
+	if err != nil {
+		return err
+	}
+
+	if !v.IsSet(profile) {
+		return fmt.Errorf("profile %v not found", profile)
+	}
+
+	// Unmarshal profile into profile struct
+	c.ProfileName = profile
+	err = v.UnmarshalKey(profile, &c.Profile)
+	if err != nil {
+		return err
+	}
+
+	// Try to determine what the default
+	c.DefaultProfile = v.GetString("default")
+	if c.DefaultProfile == "" {
+		c.DefaultProfile = v.GetString("default." + c.Profile.DbtProfile)
+	}
+
+	// Load default parameters
+	c.HomeDir = home
+
+	return LoadDefaults(c)
+}
@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const mask = "[REDACTED]"
+
+var (
+	mu      sync.RWMutex
+	secrets = map[string]bool{}
+
+	// Credential-carrying fields, in the shapes they take in JSON bodies, form and query
+	// encodings, cookies and Go struct dumps
+	patterns = []struct {
+		re          *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`("(?:access_token|refresh_token|id_token|sessionToken|stateToken|password|passCode|code|code_verifier|token)"\s*:\s*)"[^"]*"`), `$1"` + mask + `"`},
+		{regexp.MustCompile(`\b((?:access_token|refresh_token|id_token|sessionToken|stateToken|password|passCode|code|code_verifier|token|sid)=)[^&\s"',;]+`), `${1}` + mask},
+		{regexp.MustCompile(`\b((?:AccessToken|RefreshToken|IDToken|SessionToken|StateToken|SessionID|Password|Code|CodeVerifier):)[^\s}]+`), `${1}` + mask},
+		{regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`), `${1}` + mask},
+		{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`), mask},
+	}
+)
+
+// Redact registers a secret value, such as a password or token, to be masked wherever it appears in log output
+func Redact(secret string) {
+	// Very short values would mask unrelated output
+	if len(secret) < 4 {
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	secrets[secret] = true
+}
+
+// RedactString masks registered secrets and credential-carrying fields in s
+func RedactString(s string) string {
+	s = redactSecrets(s)
+
+	for _, p := range patterns {
+		s = p.re.ReplaceAllString(s, p.replacement)
+	}
+
+	return s
+}
+
+// redactSecrets masks every byte covered by a registered secret before replacing any of
+// them, so that secrets which contain or overlap one another are masked whole, whatever
+// order they're found in. Runs of covered bytes become a single mask
+func redactSecrets(s string) string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var covered []bool
+	for secret := range secrets {
+		for i := strings.Index(s, secret); i >= 0; {
+			if covered == nil {
+				covered = make([]bool, len(s))
+			}
+			for j := i; j < i+len(secret); j++ {
+				covered[j] = true
+			}
+
+			next := strings.Index(s[i+1:], secret)
+			if next < 0 {
+				break
+			}
+			i += 1 + next
+		}
+	}
+
+	if covered == nil {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if !covered[i] {
+			b.WriteByte(s[i])
+		} else if i == 0 || !covered[i-1] {
+			b.WriteString(mask)
+		}
+	}
+
+	return b.String()
+}
+
+type redactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter wraps a log output so that every entry is redacted before it's written
+func NewRedactingWriter(w io.Writer) io.Writer {
+	return &redactingWriter{w: w}
+}
+
+func (r *redactingWriter) Write(p []byte) (int, error) {
+	_, err := io.WriteString(r.w, RedactString(string(p)))
+	if err != nil {
+		return 0, err
+	}
+
+	// Report the original length, as callers expect all of p to have been consumed
+	return len(p), nil
+}
@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	// Secrets are registered globally, so each case uses values no other case contains
+	Redact("hunter2-password")
+	Redact("password-hunter2-password-suffix")
+	Redact("overlap-left-")
+	Redact("-left-right-overlap")
+	Redact("aaaa")
+	Redact("abc")
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "secret", in: "password is hunter2-password.", want: "password is [REDACTED]."},
+		{name: "secret containing another", in: "got password-hunter2-password-suffix", want: "got [REDACTED]"},
+		{name: "partly overlapping secrets", in: "x overlap-left-right-overlap y", want: "x [REDACTED] y"},
+		{name: "overlapping occurrences", in: "aaaaaa", want: "[REDACTED]"},
+		{name: "adjacent occurrences", in: "hunter2-passwordhunter2-password", want: "[REDACTED]"},
+		{name: "too short to register", in: "abc", want: "abc"},
+		{name: "no secrets", in: "nothing to see", want: "nothing to see"},
+		{name: "JSON field", in: `{"access_token":"xyz","scope":"openid"}`, want: `{"access_token":"[REDACTED]","scope":"openid"}`},
+		{name: "form field", in: "grant_type=authorization_code&code=xyz&state=1", want: "grant_type=authorization_code&code=[REDACTED]&state=1"},
+		{name: "struct dump", in: "{AccessToken:xyz ExpiresIn:600}", want: "{AccessToken:[REDACTED] ExpiresIn:600}"},
+		{name: "bearer", in: "Authorization: Bearer xyz.abc", want: "Authorization: Bearer [REDACTED]"},
+		{name: "JWT", in: "token eyJhbGciOi.eyJzdWIiOi.c2lnbmF0dXJl here", want: "token [REDACTED] here"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactString(tt.in); got != tt.want {
+				t.Errorf("RedactString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	Redact("writer-secret-value")
+
+	var buf bytes.Buffer
+	w := NewRedactingWriter(&buf)
+
+	in := []byte("2021-01-01 [DEBUG] login: password=writer-secret-value user=gimme\n")
+	n, err := w.Write(in)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	// The whole input counts as written, although the output is a different length
+	if n != len(in) {
+		t.Errorf("Write() = %v, want %v", n, len(in))
+	}
+
+	want := "2021-01-01 [DEBUG] login: password=[REDACTED] user=gimme\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
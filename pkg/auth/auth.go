@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -13,11 +16,13 @@ import (
 	"time"
 
 	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/internal/logging"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/secrets"
 	"github.com/HGInsights/gimme-snowflake-creds/pkg/utils"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
 	"github.com/HGInsights/gimme-snowflake-creds/pkg/verifier"
 	"github.com/google/uuid"
 	"github.com/manifoldco/promptui"
-	"github.com/zalando/go-keyring"
 )
 
 type authnResponse struct {
@@ -48,14 +53,23 @@ type verifyResponse struct {
 
 type authorizeResponse struct {
 	State        string
+	Nonce        string
 	Code         string
 	CodeVerifier string
+	SessionID    string
 }
 
 type tokenResponse struct {
-	ExpiresIn   int    `json:"expires_in"`
-	Scope       string `json:"scope"`
-	AccessToken string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Scope        string `json:"scope"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	IDToken      string `json:"id_token"`
+}
+
+type tokenClaims struct {
+	Nonce string `json:"nonce"`
+	Exp   int64  `json:"exp"`
 }
 
 func Auth(c config.Configuration) (*config.Credentials, error) {
@@ -78,14 +92,21 @@ func Auth(c config.Configuration) (*config.Credentials, error) {
 
 		if authn.Status == "SUCCESS" {
 			// Retrieve OAuth token
-			token, err := oauthToken(c, nil)
+			token, err := oauthToken(c, url.Values{
+				"grant_type": {"password"},
+				"username":   {c.Profile.Username},
+				"password":   {c.Profile.Password},
+			}, "")
 			if err != nil {
+				reportTokenError(c, err)
 				c.Logger.Debug("Unable to return OAuth token", "error", err)
 				os.Exit(0)
 			}
 
 			p.ExpiresIn = token.ExpiresIn
+			p.ExpiresAt = expiresAt(token.AccessToken, token.ExpiresIn)
 			p.AccessToken = token.AccessToken
+			p.RefreshToken = token.RefreshToken
 
 			return p, nil
 		} else if authn.Status == "MFA_REQUIRED" {
@@ -139,14 +160,23 @@ func Auth(c config.Configuration) (*config.Credentials, error) {
 			}
 
 			// Retrieve OAuth token
-			token, err := oauthToken(c, auth)
+			token, err := oauthToken(c, url.Values{
+				"grant_type":    {"authorization_code"},
+				"code":          {auth.Code},
+				"code_verifier": {auth.CodeVerifier},
+				"redirect_uri":  {c.Profile.RedirectURI},
+			}, auth.Nonce)
 			if err != nil {
+				reportTokenError(c, err)
 				c.Logger.Debug("Unable to return OAuth token", "error", err)
 				os.Exit(0)
 			}
 
 			p.ExpiresIn = token.ExpiresIn
+			p.ExpiresAt = expiresAt(token.AccessToken, token.ExpiresIn)
 			p.AccessToken = token.AccessToken
+			p.RefreshToken = token.RefreshToken
+			p.SessionID = auth.SessionID
 
 			return p, nil
 		} else if authn.Status == "MFA_ENROLL" {
@@ -193,10 +223,14 @@ func primaryAuth(c config.Configuration) (*authnResponse, error) {
 		c.Logger.Debug("HTTP request failed", "error", err)
 		os.Exit(0)
 	}
+	recordSkew(c, resp)
 	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized {
-		fmt.Println(string(c.ColorFailure), "Invalid password!")
+		body, _ := ioutil.ReadAll(resp.Body)
+		reportError(c, body, "Invalid password!")
 		os.Exit(0)
 	} else if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		reportError(c, body, "")
 		c.Logger.Debug("Primary: HTTP is not OK", "status", resp.StatusCode, "error", err)
 		os.Exit(0)
 	}
@@ -212,6 +246,8 @@ func primaryAuth(c config.Configuration) (*authnResponse, error) {
 		c.Logger.Debug("Unable to unmarshal response body", "error", err)
 		os.Exit(0)
 	}
+	logging.Redact(r.StateToken)
+	logging.Redact(r.SessionToken)
 
 	return r, nil
 }
@@ -241,10 +277,14 @@ func verifyMFA(c config.Configuration, authn *authnResponse, factor *factor, cha
 		c.Logger.Debug("HTTP request failed", "error", err)
 		os.Exit(0)
 	}
+	recordSkew(c, resp)
 	if resp.StatusCode == http.StatusForbidden {
-		fmt.Println(string(c.ColorFailure), "Invalid challenge!")
+		body, _ := ioutil.ReadAll(resp.Body)
+		reportError(c, body, "Invalid challenge!")
 		os.Exit(0)
 	} else if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		reportError(c, body, "")
 		c.Logger.Debug("Verify: HTTP is not OK", "status", resp.StatusCode, "error", err)
 		os.Exit(0)
 	}
@@ -260,6 +300,7 @@ func verifyMFA(c config.Configuration, authn *authnResponse, factor *factor, cha
 		c.Logger.Debug("Unable to unmarshal response body", "error", err)
 		os.Exit(0)
 	}
+	logging.Redact(r.SessionToken)
 
 	return r, nil
 }
@@ -268,7 +309,11 @@ func authCode(c config.Configuration, verify *verifyResponse) (*authorizeRespons
 	uri := c.Profile.IssuerURL + "/v1/authorize"
 
 	r := new(authorizeResponse)
-	scope := "session:role-any"
+	scope := scopes(c, "openid session:role-any")
+
+	// Both are echoed back by the authorization server and must match what was sent
+	state := uuid.NewString()
+	nonce := uuid.NewString()
 
 	// PKCE code verifier and code challenge generation
 	v, err := verifier.CreateCodeVerifier()
@@ -276,6 +321,7 @@ func authCode(c config.Configuration, verify *verifyResponse) (*authorizeRespons
 		return nil, err
 	}
 	r.CodeVerifier = v.String()
+	logging.Redact(r.CodeVerifier)
 	codeChallenge := v.CodeChallengeS256()
 
 	payload := url.Values{}
@@ -283,7 +329,8 @@ func authCode(c config.Configuration, verify *verifyResponse) (*authorizeRespons
 	payload.Set("response_type", "code")
 	payload.Set("scope", scope)
 	payload.Set("redirect_uri", c.Profile.RedirectURI)
-	payload.Set("state", uuid.NewString())
+	payload.Set("state", state)
+	payload.Set("nonce", nonce)
 	payload.Set("sessionToken", verify.SessionToken)
 	payload.Set("code_challenge", codeChallenge)
 	payload.Set("code_challenge_method", "S256")
@@ -303,7 +350,10 @@ func authCode(c config.Configuration, verify *verifyResponse) (*authorizeRespons
 		c.Logger.Debug("HTTP request failed", "error", err)
 		os.Exit(0)
 	}
+	recordSkew(c, resp)
 	if resp.StatusCode != http.StatusFound {
+		body, _ := ioutil.ReadAll(resp.Body)
+		reportError(c, body, "")
 		c.Logger.Debug("Authorize: HTTP is not 302!", "status", resp.StatusCode, "error", err)
 		os.Exit(0)
 	}
@@ -314,33 +364,56 @@ func authCode(c config.Configuration, verify *verifyResponse) (*authorizeRespons
 		os.Exit(0)
 	}
 
+	// Authorization errors are returned to the redirect URI rather than in the body
+	if code := location.Query().Get("error"); code != "" {
+		fmt.Println(string(c.ColorFailure), describeOAuthError(code, location.Query().Get("error_description")))
+		os.Exit(0)
+	}
+
 	r.State = location.Query().Get("state")
+	r.Nonce = nonce
 	r.Code = location.Query().Get("code")
+	logging.Redact(r.Code)
+
+	if r.State != state {
+		fmt.Println(string(c.ColorFailure), "Authorization response state mismatch!")
+		return nil, errors.New("authorization response state mismatch")
+	}
+
+	// Keep the Okta session cookie around so that the session can be ended on logout
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "sid" {
+			r.SessionID = cookie.Value
+			logging.Redact(r.SessionID)
+		}
+	}
 
 	return r, nil
 }
 
-func oauthToken(c config.Configuration, auth *authorizeResponse) (*tokenResponse, error) {
+// scopes adds `offline_access` to the requested scopes for profiles with `refresh-tokens: true`,
+// as Okta only issues refresh tokens when it's requested
+func scopes(c config.Configuration, scope string) string {
+	if c.Profile.RefreshTokens {
+		return scope + " offline_access"
+	}
+
+	return scope
+}
+
+// oauthToken exchanges a grant for tokens, checking the ID token's nonce when one was sent.
+// It returns errors rather than exiting, as Refresh uses it from long-running processes
+func oauthToken(c config.Configuration, grant url.Values, nonce string) (*tokenResponse, error) {
 	uri := c.Profile.IssuerURL + "/v1/token"
 
 	r := new(tokenResponse)
-	scope := "session:role-any"
+	scope := scopes(c, "session:role-any")
 
 	payload := url.Values{}
-
-	if auth != nil {
-		payload.Set("client_id", c.Profile.ClientID)
-		payload.Set("grant_type", "authorization_code")
-		payload.Set("code", auth.Code)
-		payload.Set("code_verifier", auth.CodeVerifier)
-		payload.Set("redirect_uri", c.Profile.RedirectURI)
-		payload.Set("scope", scope)
-	} else {
-		payload.Set("client_id", c.Profile.ClientID)
-		payload.Set("grant_type", "password")
-		payload.Set("username", c.Profile.Username)
-		payload.Set("password", c.Profile.Password)
-		payload.Set("scope", scope)
+	payload.Set("client_id", c.Profile.ClientID)
+	payload.Set("scope", scope)
+	for key, values := range grant {
+		payload[key] = values
 	}
 
 	req, _ := http.NewRequest("POST", uri, strings.NewReader(payload.Encode()))
@@ -354,33 +427,65 @@ func oauthToken(c config.Configuration, auth *authorizeResponse) (*tokenResponse
 
 	resp, err := h.Do(req)
 	if err != nil {
-		c.Logger.Debug("HTTP request failed", "error", err, "response", resp)
-		os.Exit(0)
-	}
-	if resp.StatusCode == http.StatusBadRequest {
-		fmt.Println(string(c.ColorFailure), "Bad request: maybe check Okta privileges?")
-		c.Logger.Debug("OAuth: HTTP 400", "error", err, "response", resp)
-		os.Exit(0)
-	} else if resp.StatusCode != http.StatusOK {
-		c.Logger.Debug("OAuth: HTTP is not OK", "status", resp.StatusCode, "error", err)
-		os.Exit(0)
+		c.Logger.Debug("HTTP request failed", "error", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
+	recordSkew(c, resp)
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		c.Logger.Debug("Unable to read response body", "error", err)
-		os.Exit(0)
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		c.Logger.Debug("OAuth: HTTP is not OK", "status", resp.StatusCode, "grant", grant.Get("grant_type"))
+		return nil, &tokenError{status: resp.StatusCode, body: body}
 	}
 
 	err = json.Unmarshal(body, &r)
 	if err != nil {
 		c.Logger.Debug("Unable to unmarshal response body", "error", err)
-		os.Exit(0)
+		return nil, err
+	}
+	logging.Redact(r.AccessToken)
+	logging.Redact(r.RefreshToken)
+	logging.Redact(r.IDToken)
+
+	// The ID token issued for an authorization code must carry the nonce sent with the authorization request
+	if nonce != "" {
+		claims, err := parseClaims(r.IDToken)
+		if err != nil || claims.Nonce != nonce {
+			return nil, errNonceMismatch
+		}
 	}
 
 	return r, nil
 }
 
+// parseClaims decodes the payload of a JWT without verifying its signature
+func parseClaims(token string) (*tokenClaims, error) {
+	claims := new(tokenClaims)
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(payload, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
 func factorPush(c config.Configuration, authn *authnResponse, factor *factor) error {
 	uri := factor.Links.Verify.VerifyURL
 
@@ -402,6 +507,7 @@ func factorPush(c config.Configuration, authn *authnResponse, factor *factor) er
 		c.Logger.Debug("HTTP request failed", "error", err)
 		os.Exit(0)
 	}
+	recordSkew(c, resp)
 	if resp.StatusCode == http.StatusTooManyRequests {
 		fmt.Println(string(c.ColorFailure), "Slow down! Wait a few moments...")
 		os.Exit(0)
@@ -418,31 +524,36 @@ func factorPush(c config.Configuration, authn *authnResponse, factor *factor) er
 }
 
 func retrievePassword(c config.Configuration) (config.Configuration, error) {
-	forget := func(username string) error {
-		err := keyring.Delete("gimme-snowflake-creds", username)
+	secretStore, err := secrets.PasswordStore(c)
+	if err != nil {
+		return c, err
+	}
+
+	forget := func(key string) error {
+		err := secretStore.Delete(key)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println(string(c.ColorSuccess), "Password deleted from keyring")
+		fmt.Println(string(c.ColorSuccess), "Password deleted from", secretStore.Name())
 
 		return nil
 	}
 
 	store := func(password string) error {
-		err := keyring.Set("gimme-snowflake-creds", c.Profile.Username, password)
+		err := secretStore.Set(secrets.Key(c), password)
 		if err != nil {
 			return err
 		}
 
-		fmt.Println(string(c.ColorSuccess), "Password saved to keyring")
+		fmt.Println(string(c.ColorSuccess), "Password saved to", secretStore.Name())
 
 		return nil
 	}
 
 	prompt := func() (string, error) {
 		passwordLabel := "Okta password for " + c.Profile.Username
-		keyringLabel := "Save this password in the keyring"
+		keyringLabel := "Save this password in the " + secretStore.Name()
 
 		validatePassword := func(input string) error {
 			if len(input) == 0 {
@@ -478,6 +589,11 @@ func retrievePassword(c config.Configuration) (config.Configuration, error) {
 			return "", err
 		}
 
+		// Read-only stores are managed outside of this tool
+		if !secretStore.Writable() {
+			return password, nil
+		}
+
 		keyring, err := keyringPrompt.Run()
 		confirmed := !errors.Is(err, promptui.ErrAbort)
 		if err != nil && confirmed {
@@ -491,16 +607,38 @@ func retrievePassword(c config.Configuration) (config.Configuration, error) {
 		return password, nil
 	}
 
-	if c.Forget {
-		err := forget(c.Profile.Username)
+	err = secrets.Migrate(secretStore, c)
+	if err != nil {
+		c.Logger.Debug("Unable to migrate password to namespaced entry", "error", err)
+	}
+
+	if c.Forget && secretStore.Writable() {
+		err := forget(secrets.Key(c))
 		if err != nil {
 			c.Logger.Debug("Forget failed", "error", err)
 		}
 	}
 
-	password, err := keyring.Get("gimme-snowflake-creds", c.Profile.Username)
+	// Explicitly supplied passwords take precedence and are never stored
+	if c.PasswordStdin {
+		password, err := readPasswordStdin()
+		if err != nil {
+			fmt.Println(string(c.ColorFailure), "Unable to read password from stdin!")
+			return c, err
+		}
+
+		c.Profile.Password = password
+		logging.Redact(password)
+
+		// Stdin is used up, so any MFA prompts read the terminal
+		reopenTerminal(c)
+
+		return c, nil
+	}
+
+	password, err := secretStore.Get(secrets.Key(c))
 	if err != nil {
-		c.Logger.Debug("Password not present in keyring")
+		c.Logger.Debug("Password not present in secret store", "store", secretStore.Name(), "error", err)
 	}
 
 	if password == "" {
@@ -511,16 +649,32 @@ func retrievePassword(c config.Configuration) (config.Configuration, error) {
 		}
 
 		c.Profile.Password = password
+		logging.Redact(password)
 
 		return c, nil
 	}
 
-	c.Logger.Debug("Password present in keyring")
+	c.Logger.Debug("Password present in secret store", "store", secretStore.Name())
 	c.Profile.Password = password
+	logging.Redact(password)
 
 	return c, nil
 }
 
+func readPasswordStdin() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+
+	return password, nil
+}
+
 func factorSelect(c config.Configuration, resp *authnResponse) (*factor, error) {
 	factors := []string{}
 
@@ -530,9 +684,15 @@ func factorSelect(c config.Configuration, resp *authnResponse) (*factor, error)
 		factors = append(factors, f.FactorType+" ("+f.Provider+")")
 	}
 
+	err := canPrompt(c)
+	if err != nil {
+		return nil, err
+	}
+
 	prompt := promptui.Select{
 		Label: "Select MFA method",
 		Items: factors,
+		Stdin: promptStdin,
 	}
 
 	_, result, err := prompt.Run()
@@ -556,6 +716,22 @@ func factorChallenge(c config.Configuration, factor *factor) (string, error) {
 		return "", nil
 	}
 
+	// Vault users may keep their TOTP seed alongside their password
+	if factor.FactorType == "token:software:totp" && c.Profile.SecretStore == "file" {
+		seed, err := vault.Open(c).Get(vault.TOTPSeeds, secrets.Key(c))
+		if err == nil {
+			// Codes are generated with Okta's clock, so a drifting local clock doesn't matter
+			code, err := totpCode(seed, ServerNow())
+			if err == nil {
+				fmt.Println(string(c.ColorSuccess), "MFA code generated from vault")
+				return code, nil
+			}
+			c.Logger.Debug("Unable to generate TOTP code", "error", err)
+		} else {
+			c.Logger.Debug("No TOTP seed in vault", "error", err)
+		}
+	}
+
 	validate := func(input string) error {
 		if len(input) == 0 {
 			return errors.New("MFA code must not be empty")
@@ -563,10 +739,16 @@ func factorChallenge(c config.Configuration, factor *factor) (string, error) {
 		return nil
 	}
 
+	err := canPrompt(c)
+	if err != nil {
+		return "", err
+	}
+
 	prompt := promptui.Prompt{
 		Label:    "MFA code",
 		Validate: validate,
 		Mask:     '*',
+		Stdin:    promptStdin,
 	}
 
 	result, err := prompt.Run()
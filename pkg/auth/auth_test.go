@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/hashicorp/go-hclog"
+)
+
+// testConfig returns an OAuth profile whose Okta org and authorization server are served by h
+func testConfig(t *testing.T, h http.HandlerFunc) config.Configuration {
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	return config.Configuration{
+		ProfileName: "test",
+		HomeDir:     t.TempDir(),
+		Logger:      hclog.NewNullLogger(),
+		Profile: config.Profile{
+			OAuth:       true,
+			OktaOrg:     server.URL,
+			IssuerURL:   server.URL + "/oauth2/default",
+			ClientID:    "client",
+			RedirectURI: "http://localhost:8080/callback",
+			Username:    "gimme-user@example.com",
+		},
+	}
+}
+
+// testJWT returns an unsigned JWT carrying the given claims
+func testJWT(claims map[string]interface{}) string {
+	payload, _ := json.Marshal(claims)
+
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString(payload) + ".c2lnbmF0dXJl"
+}
+
+func TestAuthCode(t *testing.T) {
+	tests := []struct {
+		name          string
+		refreshTokens bool
+		state         func(sent string) string
+		wantErr       bool
+		wantScope     string
+	}{
+		{name: "state echoed", state: func(sent string) string { return sent }, wantScope: "openid session:role-any"},
+		{name: "refresh tokens", refreshTokens: true, state: func(sent string) string { return sent }, wantScope: "openid session:role-any offline_access"},
+		{name: "state mismatch", state: func(sent string) string { return "forged" }, wantErr: true},
+		{name: "state missing", state: func(sent string) string { return "" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent url.Values
+			c := testConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				sent = r.URL.Query()
+
+				location := url.Values{}
+				location.Set("code", "authorization-code")
+				location.Set("state", tt.state(sent.Get("state")))
+
+				http.SetCookie(w, &http.Cookie{Name: "sid", Value: "okta-session"})
+				http.Redirect(w, r, sent.Get("redirect_uri")+"?"+location.Encode(), http.StatusFound)
+			})
+			c.Profile.RefreshTokens = tt.refreshTokens
+
+			r, err := authCode(c, &verifyResponse{SessionToken: "session-token"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("authCode() error = nil, want a state mismatch")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("authCode() error = %v", err)
+			}
+
+			if got := sent.Get("scope"); got != tt.wantScope {
+				t.Errorf("scope = %q, want %q", got, tt.wantScope)
+			}
+			if sent.Get("state") == "" || sent.Get("nonce") == "" || sent.Get("state") == sent.Get("nonce") {
+				t.Errorf("state = %q and nonce = %q, want two distinct values", sent.Get("state"), sent.Get("nonce"))
+			}
+			if r.Nonce != sent.Get("nonce") {
+				t.Errorf("Nonce = %q, want the sent nonce %q", r.Nonce, sent.Get("nonce"))
+			}
+			if r.Code != "authorization-code" || r.SessionID != "okta-session" {
+				t.Errorf("Code = %q, SessionID = %q", r.Code, r.SessionID)
+			}
+			if sent.Get("code_challenge_method") != "S256" || sent.Get("code_challenge") == "" || r.CodeVerifier == "" {
+				t.Errorf("PKCE parameters missing")
+			}
+		})
+	}
+}
+
+func TestOAuthToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		nonce         string
+		refreshTokens bool
+		status        int
+		body          string
+		wantScope     string
+		wantErr       string
+	}{
+		{
+			name:      "no nonce expected",
+			status:    http.StatusOK,
+			body:      `{"access_token":"access","expires_in":3600}`,
+			wantScope: "session:role-any",
+		},
+		{
+			name:      "nonce matches",
+			nonce:     "expected",
+			status:    http.StatusOK,
+			body:      `{"access_token":"access","id_token":"` + testJWT(map[string]interface{}{"nonce": "expected"}) + `"}`,
+			wantScope: "session:role-any",
+		},
+		{
+			name:    "nonce mismatch",
+			nonce:   "expected",
+			status:  http.StatusOK,
+			body:    `{"access_token":"access","id_token":"` + testJWT(map[string]interface{}{"nonce": "replayed"}) + `"}`,
+			wantErr: "id token nonce mismatch",
+		},
+		{
+			name:    "ID token missing",
+			nonce:   "expected",
+			status:  http.StatusOK,
+			body:    `{"access_token":"access"}`,
+			wantErr: "id token nonce mismatch",
+		},
+		{
+			name:          "refresh tokens",
+			refreshTokens: true,
+			status:        http.StatusOK,
+			body:          `{"access_token":"access","refresh_token":"refresh"}`,
+			wantScope:     "session:role-any offline_access",
+		},
+		{
+			name:    "OAuth error",
+			status:  http.StatusBadRequest,
+			body:    `{"error":"invalid_grant","error_description":"The refresh token is invalid or expired."}`,
+			wantErr: "token request failed: invalid_grant",
+		},
+		{
+			name:    "unrecognized error",
+			status:  http.StatusBadRequest,
+			body:    `<html>Bad Request</html>`,
+			wantErr: "token request returned HTTP 400",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent url.Values
+			c := testConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				sent = r.PostForm
+
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+			c.Profile.RefreshTokens = tt.refreshTokens
+
+			r, err := oauthToken(c, url.Values{"grant_type": {"authorization_code"}, "code": {"code"}}, tt.nonce)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("oauthToken() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("oauthToken() error = %v", err)
+			}
+
+			if r.AccessToken != "access" {
+				t.Errorf("AccessToken = %q, want %q", r.AccessToken, "access")
+			}
+			if got := sent.Get("scope"); got != tt.wantScope {
+				t.Errorf("scope = %q, want %q", got, tt.wantScope)
+			}
+			if sent.Get("grant_type") != "authorization_code" || sent.Get("client_id") != "client" {
+				t.Errorf("grant not sent: %v", sent)
+			}
+		})
+	}
+}
+
+func TestTokenErrorGuidance(t *testing.T) {
+	err := &tokenError{status: http.StatusBadRequest, body: []byte(`{"error":"invalid_scope","error_description":"The requested scope is invalid."}`)}
+
+	// Logs and callers get the short error, users the guidance
+	if got := err.Error(); got != "token request failed: invalid_scope" {
+		t.Errorf("Error() = %q, want %q", got, "token request failed: invalid_scope")
+	}
+	if got := describeError(err.body); !strings.HasPrefix(got, "Invalid scope:") || !strings.HasSuffix(got, "[invalid_scope]") {
+		t.Errorf("describeError() = %q, want the invalid_scope guidance", got)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "rotated", body: `{"access_token":"access","expires_in":3600,"refresh_token":"rotated"}`, want: "rotated"},
+		{name: "not rotated", body: `{"access_token":"access","expires_in":3600}`, want: "original"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent url.Values
+			c := testConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				sent = r.PostForm
+
+				w.Write([]byte(tt.body))
+			})
+
+			p, err := Refresh(c, "original")
+			if err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+
+			if sent.Get("grant_type") != "refresh_token" || sent.Get("refresh_token") != "original" {
+				t.Errorf("grant = %v, want the refresh token grant", sent)
+			}
+			if p.AccessToken != "access" || p.RefreshToken != tt.want {
+				t.Errorf("Refresh() = %q, %q, want %q, %q", p.AccessToken, p.RefreshToken, "access", tt.want)
+			}
+			if p.ExpiresAt.IsZero() {
+				t.Errorf("ExpiresAt isn't set")
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	tests := []struct {
+		name  string
+		links string
+		want  func(org string) string
+	}{
+		{
+			name:  "Okta IdP",
+			links: `[{"rel":"` + issuerRel + `","href":"https://example.okta.com/sso/idps/OKTA","properties":{"okta:idp:type":"OKTA"}}]`,
+			want:  func(org string) string { return "https://example.okta.com" },
+		},
+		{
+			name:  "external IdP",
+			links: `[{"rel":"` + issuerRel + `","href":"https://idp.example.com/sso","properties":{"okta:idp:type":"SAML2"}}]`,
+			want:  func(org string) string { return org },
+		},
+		{
+			name:  "no IdP",
+			links: `[]`,
+			want:  func(org string) string { return "" },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resource string
+			c := testConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				resource = r.URL.Query().Get("resource")
+				w.Write([]byte(`{"subject":"okta:acct:gimme-user@example.com","links":` + tt.links + `}`))
+			})
+
+			org, err := Discover(c, "gimme-user@example.com")
+			if want := tt.want(c.Profile.OktaOrg); org != want || (want == "") != (err != nil) {
+				t.Errorf("Discover() = %q, %v, want %q", org, err, want)
+			}
+			if resource != "okta:acct:gimme-user@example.com" {
+				t.Errorf("resource = %q", resource)
+			}
+		})
+	}
+}
+
+func TestDiscoverWithoutOrg(t *testing.T) {
+	c := testConfig(t, func(w http.ResponseWriter, r *http.Request) {})
+	c.Profile.OktaOrg = ""
+
+	// The email domain is asked, and nothing is guessed when it doesn't answer
+	_, err := Discover(c, "gimme-user@invalid.invalid")
+	if err == nil || !strings.Contains(err.Error(), "couldn't discover the Okta org for invalid.invalid") {
+		t.Errorf("Discover() error = %v, want a discovery error", err)
+	}
+
+	if _, err := Discover(c, "not-an-email"); err == nil {
+		t.Errorf("Discover() of an invalid email error = nil")
+	}
+}
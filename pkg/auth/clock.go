@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// SkewThreshold is how far the local clock may drift from Okta's before a warning is shown
+const SkewThreshold = 30 * time.Second
+
+var (
+	skew       time.Duration
+	skewWarned bool
+	skewMu     sync.Mutex
+)
+
+// recordSkew compares the Date header of an Okta response with the local clock. Large skews
+// are warned about through the logger rather than printed, as long-running processes use it too
+func recordSkew(c config.Configuration, resp *http.Response) {
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	skewMu.Lock()
+	defer skewMu.Unlock()
+
+	// The Date header only has second precision, which is well within the threshold
+	skew = time.Until(date)
+	c.Logger.Debug("Clock skew", "skew", skew)
+
+	if !skewWarned && (skew > SkewThreshold || skew < -SkewThreshold) {
+		c.Logger.Warn("Local clock is off from Okta: consider syncing it", "skew", skew.Round(time.Second))
+		skewWarned = true
+	}
+}
+
+// Skew returns how far Okta's clock is ahead of the local clock, as of the last response
+func Skew() time.Duration {
+	skewMu.Lock()
+	defer skewMu.Unlock()
+
+	return skew
+}
+
+// ServerNow returns the current time according to Okta's clock
+func ServerNow() time.Time {
+	return time.Now().Add(Skew())
+}
+
+// expiresAt converts a token's expiry into local time, preferring the `exp` claim of
+// JWT access tokens, which is stamped with Okta's clock
+func expiresAt(token string, expiresIn int) time.Time {
+	claims, err := parseClaims(token)
+	if err == nil && claims.Exp != 0 {
+		return time.Unix(claims.Exp, 0).Add(-Skew())
+	}
+
+	return time.Now().Add(time.Duration(expiresIn) * time.Second)
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/hashicorp/go-hclog"
+)
+
+// resetSkew clears the recorded skew before and after a test
+func resetSkew(t *testing.T) {
+	reset := func() {
+		skewMu.Lock()
+		defer skewMu.Unlock()
+
+		skew = 0
+		skewWarned = false
+	}
+
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestRecordSkew(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset time.Duration
+		date   string
+		want   time.Duration
+	}{
+		{name: "in sync", offset: 0, want: 0},
+		{name: "Okta ahead", offset: time.Hour, want: time.Hour},
+		{name: "Okta behind", offset: -10 * time.Minute, want: -10 * time.Minute},
+		{name: "no Date header", date: "-", want: 0},
+		{name: "invalid Date header", date: "yesterday", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetSkew(t)
+
+			date := tt.date
+			if date == "" {
+				date = time.Now().Add(tt.offset).UTC().Format(http.TimeFormat)
+			} else if date == "-" {
+				date = ""
+			}
+
+			resp := &http.Response{Header: http.Header{"Date": {date}}}
+			recordSkew(config.Configuration{Logger: hclog.NewNullLogger()}, resp)
+
+			// The Date header only has second precision
+			if got := Skew(); got < tt.want-2*time.Second || got > tt.want+time.Second {
+				t.Errorf("Skew() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	resetSkew(t)
+
+	skewMu.Lock()
+	skew = time.Hour
+	skewMu.Unlock()
+
+	// The exp claim is Okta's time, so it's moved back by the skew
+	exp := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	token := testJWT(map[string]interface{}{"exp": exp.Unix()})
+	if got, want := expiresAt(token, 60), exp.Add(-time.Hour); !got.Equal(want) {
+		t.Errorf("expiresAt() = %v, want %v", got, want)
+	}
+
+	// Opaque tokens fall back to expires_in, which is relative and needs no correction
+	got := expiresAt("opaque", 600)
+	if want := time.Now().Add(600 * time.Second); got.Before(want.Add(-time.Second)) || got.After(want) {
+		t.Errorf("expiresAt() = %v, want %v", got, want)
+	}
+
+	if got, want := ServerNow(), time.Now().Add(time.Hour); got.Before(want.Add(-time.Second)) || got.After(want.Add(time.Second)) {
+		t.Errorf("ServerNow() = %v, want %v", got, want)
+	}
+}
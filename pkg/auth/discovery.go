@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+const issuerRel = "http://openid.net/specs/connect/1.0/issuer"
+
+// Bootstrap holds the org-level settings needed to create a new profile
+type Bootstrap struct {
+	OktaOrg     string `json:"okta-org"`
+	IssuerURL   string `json:"issuer"`
+	ClientID    string `json:"client-id"`
+	RedirectURI string `json:"redirect-uri"`
+}
+
+type webfingerResponse struct {
+	Subject string `json:"subject"`
+	Links   []struct {
+		Rel        string            `json:"rel"`
+		Href       string            `json:"href"`
+		Properties map[string]string `json:"properties"`
+	} `json:"links"`
+}
+
+// FetchBootstrap retrieves team-published org-level settings from a JSON document
+func FetchBootstrap(c config.Configuration, uri string) (*Bootstrap, error) {
+	r := new(Bootstrap)
+
+	body, err := getJSON(c, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(body, r)
+	if err != nil {
+		c.Logger.Debug("Unable to unmarshal bootstrap document", "error", err)
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Discover finds the Okta org that authenticates the given email address via WebFinger,
+// asking the profile's org or, without one, the email domain itself, e.g. user@example.com
+// is looked up at https://example.com. Orgs are never guessed, so when the domain doesn't
+// answer the org must be passed with --okta-org
+func Discover(c config.Configuration, email string) (string, error) {
+	at := strings.LastIndex(email, "@")
+	if at < 1 || at == len(email)-1 {
+		return "", fmt.Errorf("invalid email address: %v", email)
+	}
+
+	org := strings.TrimSuffix(c.Profile.OktaOrg, "/")
+	if org == "" {
+		org = "https://" + email[at+1:]
+	}
+
+	payload := url.Values{}
+	payload.Set("resource", "okta:acct:"+email)
+	payload.Set("rel", issuerRel)
+
+	// Most email domains don't serve WebFinger, which isn't worth reporting as a network error
+	body, err := fetchJSON(c, org+"/.well-known/webfinger?"+payload.Encode())
+	if err != nil {
+		return "", fmt.Errorf("couldn't discover the Okta org for %v: %w", email[at+1:], err)
+	}
+
+	r := new(webfingerResponse)
+	err = json.Unmarshal(body, r)
+	if err != nil {
+		c.Logger.Debug("Unable to unmarshal WebFinger response", "error", err)
+		return "", err
+	}
+
+	for _, link := range r.Links {
+		if link.Rel != issuerRel {
+			continue
+		}
+
+		// Users routed to an external IdP still authenticate against this org
+		if link.Properties["okta:idp:type"] != "OKTA" {
+			c.Logger.Debug("User is routed to an external IdP", "idp", link.Href)
+			return org, nil
+		}
+
+		// The href is the org's IdP endpoint, e.g. https://example.okta.com/sso/idps/OKTA,
+		// of which only the origin is the org
+		href, err := url.Parse(link.Href)
+		if err != nil || href.Scheme == "" || href.Host == "" {
+			c.Logger.Debug("Unable to parse IdP link, using the queried org", "href", link.Href)
+			return org, nil
+		}
+
+		return href.Scheme + "://" + href.Host, nil
+	}
+
+	return "", errors.New("no identity provider found for " + email)
+}
+
+// getJSON fetches a document, reporting failures to the user
+func getJSON(c config.Configuration, uri string) ([]byte, error) {
+	body, err := fetchJSON(c, uri)
+
+	var status *statusError
+	if errors.As(err, &status) {
+		reportError(c, status.body, "")
+	} else if err != nil {
+		fmt.Println(string(c.ColorFailure), "Unknown error: is the network up?")
+	}
+
+	return body, err
+}
+
+// statusError is a response other than HTTP 200, keeping the body for reportError
+type statusError struct {
+	uri    string
+	status int
+	body   []byte
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%v returned HTTP %d", e.uri, e.status)
+}
+
+// fetchJSON fetches a document without printing, for lookups that are allowed to fail
+func fetchJSON(c config.Configuration, uri string) ([]byte, error) {
+	req, err := http.NewRequest("GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	h := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := h.Do(req)
+	if err != nil {
+		c.Logger.Debug("HTTP request failed", "error", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		c.Logger.Debug("Unable to read response body", "error", err)
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		c.Logger.Debug("Discovery: HTTP is not OK", "status", resp.StatusCode, "uri", uri)
+		return nil, &statusError{uri: uri, status: resp.StatusCode, body: body}
+	}
+
+	return body, nil
+}
@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// oktaError is the error payload returned by the Okta management and authentication APIs
+type oktaError struct {
+	ErrorCode    string `json:"errorCode"`
+	ErrorSummary string `json:"errorSummary"`
+	ErrorCauses  []struct {
+		ErrorSummary string `json:"errorSummary"`
+	} `json:"errorCauses"`
+}
+
+// tokenError is a failed token request. Its message is kept short for logs and callers such
+// as Refresh, while reportTokenError turns it into guidance for the user
+type tokenError struct {
+	status int
+	body   []byte
+}
+
+func (e *tokenError) Error() string {
+	a := new(oauthError)
+	if err := json.Unmarshal(e.body, a); err == nil && a.Error != "" {
+		return "token request failed: " + a.Error
+	}
+
+	return fmt.Sprintf("token request returned HTTP %d", e.status)
+}
+
+// errNonceMismatch is returned when an ID token wasn't issued for the authorization request it answers
+var errNonceMismatch = errors.New("id token nonce mismatch")
+
+// oauthError is the error payload returned by the OAuth 2.0 endpoints of an authorization server
+type oauthError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+var oktaGuidance = map[string]string{
+	"E0000004": "Authentication failed: check the username and password, or run again with --forget to replace a saved password",
+	"E0000006": "Access denied: your Okta user isn't allowed to perform this request",
+	"E0000011": "Invalid token: the Okta session expired, run again to start a new one",
+	"E0000047": "Okta API rate limit exceeded: wait a few moments and try again",
+	"E0000064": "Password expired: change your password in Okta and try again",
+	"E0000068": "Invalid MFA code: check the code and try again",
+	"E0000069": "User locked out: contact your Okta administrator or wait for the lockout to expire",
+	"E0000079": "Okta rejected the operation: the authentication transaction may have expired, try again",
+	"E0000080": "Password doesn't meet the Okta password policy requirements",
+}
+
+var oauthGuidance = map[string]string{
+	"invalid_scope":          "Invalid scope: the authorization server must grant `session:role-any` to this application, and `offline_access` with `refresh-tokens: true`, see the README prerequisites",
+	"access_denied":          "Access denied: your Okta user isn't assigned to the application or is blocked by an access policy",
+	"invalid_client":         "Invalid client: check `client-id` and `issuer-url` in your profile",
+	"unauthorized_client":    "Unauthorized client: the application doesn't allow this grant type, ask your Okta administrator to enable it",
+	"invalid_grant":          "Invalid grant: the credentials or authorization code were rejected or have expired, try again",
+	"unsupported_grant_type": "Unsupported grant type: the authorization server doesn't allow this grant type",
+	"login_required":         "Login required: the Okta session couldn't be established, try again",
+	"consent_required":       "Consent required: grant consent to the application in Okta and try again",
+}
+
+// reportError prints guidance for an error payload, falling back to the given message
+// when the payload isn't recognized
+func reportError(c config.Configuration, body []byte, fallback string) {
+	message := describeError(body)
+	if message == "" {
+		message = fallback
+	}
+	if message != "" {
+		fmt.Println(string(c.ColorFailure), message)
+	}
+}
+
+// reportTokenError prints guidance for an error returned by oauthToken
+func reportTokenError(c config.Configuration, err error) {
+	var t *tokenError
+	switch {
+	case errors.As(err, &t):
+		fallback := fmt.Sprintf("Token request failed: HTTP %d", t.status)
+		if t.status == http.StatusBadRequest {
+			fallback = "Bad request: maybe check Okta privileges?"
+		}
+		reportError(c, t.body, fallback)
+	case errors.Is(err, errNonceMismatch):
+		fmt.Println(string(c.ColorFailure), "ID token nonce mismatch: the token wasn't issued for this login, try again")
+	default:
+		fmt.Println(string(c.ColorFailure), "Unknown error: is the network up?")
+	}
+}
+
+// describeError turns an Okta or OAuth error payload into actionable guidance,
+// returning an empty string when the payload isn't recognized
+func describeError(body []byte) string {
+	o := new(oktaError)
+	if err := json.Unmarshal(body, o); err == nil && o.ErrorCode != "" {
+		return describeOktaError(o)
+	}
+
+	a := new(oauthError)
+	if err := json.Unmarshal(body, a); err == nil && a.Error != "" {
+		return describeOAuthError(a.Error, a.ErrorDescription)
+	}
+
+	return ""
+}
+
+func describeOktaError(o *oktaError) string {
+	message, ok := oktaGuidance[o.ErrorCode]
+	if !ok {
+		message = o.ErrorSummary
+	}
+
+	causes := []string{}
+	for _, cause := range o.ErrorCauses {
+		if cause.ErrorSummary != "" {
+			causes = append(causes, cause.ErrorSummary)
+		}
+	}
+	if len(causes) > 0 {
+		message += " (" + strings.Join(causes, "; ") + ")"
+	}
+
+	return message + " [" + o.ErrorCode + "]"
+}
+
+func describeOAuthError(code string, description string) string {
+	// A mismatched redirect URI is reported under several error codes
+	if strings.Contains(description, "redirect_uri") {
+		return "Redirect URI mismatch: `redirect-uri` must exactly match a sign-in redirect URI of the Okta application [" + code + "]"
+	}
+
+	message, ok := oauthGuidance[code]
+	if !ok {
+		message = description
+	} else if description != "" {
+		message += " (" + description + ")"
+	}
+
+	return message + " [" + code + "]"
+}
@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// Logout revokes every token and ends the Okta session, carrying on past failures so that
+// one dead credential doesn't leave the others live
+func Logout(c config.Configuration, t *config.Credentials) error {
+	failed := []string{}
+
+	if t.AccessToken != "" {
+		err := revokeToken(c, t.AccessToken, "access_token")
+		if err != nil {
+			c.Logger.Debug("Unable to revoke access token", "error", err)
+			failed = append(failed, "access token: "+err.Error())
+		} else {
+			fmt.Println(string(c.ColorSuccess), "Access token revoked")
+		}
+	}
+
+	if t.RefreshToken != "" {
+		err := revokeToken(c, t.RefreshToken, "refresh_token")
+		if err != nil {
+			c.Logger.Debug("Unable to revoke refresh token", "error", err)
+			failed = append(failed, "refresh token: "+err.Error())
+		} else {
+			fmt.Println(string(c.ColorSuccess), "Refresh token revoked")
+		}
+	}
+
+	if t.SessionID != "" {
+		err := endSession(c, t.SessionID)
+		if err != nil {
+			c.Logger.Debug("Unable to end Okta session", "error", err)
+			failed = append(failed, "Okta session: "+err.Error())
+		} else {
+			fmt.Println(string(c.ColorSuccess), "Okta session ended")
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New("logout incomplete: " + strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+func revokeToken(c config.Configuration, token string, tokenType string) error {
+	uri := c.Profile.IssuerURL + "/v1/revoke"
+
+	payload := url.Values{}
+	payload.Set("client_id", c.Profile.ClientID)
+	payload.Set("token", token)
+	payload.Set("token_type_hint", tokenType)
+
+	req, _ := http.NewRequest("POST", uri, strings.NewReader(payload.Encode()))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Cache-Control", "no-cache")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded;charset=UTF-8")
+
+	h := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := h.Do(req)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Unknown error: is the network up?")
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		reportError(c, body, "")
+		c.Logger.Debug("Revoke: HTTP is not OK", "status", resp.StatusCode)
+		return fmt.Errorf("revoke returned HTTP %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func endSession(c config.Configuration, sessionID string) error {
+	uri := c.Profile.OktaOrg + "/api/v1/sessions/me"
+
+	req, _ := http.NewRequest("DELETE", uri, nil)
+	req.Header.Set("Accept", "application/json")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: sessionID})
+
+	h := &http.Client{
+		Timeout: 10 * time.Second,
+	}
+
+	resp, err := h.Do(req)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Unknown error: is the network up?")
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The session may already have expired, which is as good as ending it
+	if resp.StatusCode == http.StatusNotFound {
+		c.Logger.Debug("Okta session already expired")
+		return nil
+	} else if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		c.Logger.Debug("Session: HTTP is not OK", "status", resp.StatusCode)
+		return fmt.Errorf("session delete returned HTTP %d", resp.StatusCode)
+	}
+
+	return nil
+}
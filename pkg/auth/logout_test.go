@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+func TestLogout(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials config.Credentials
+		revoke      int
+		session     int
+		want        []string
+		wantErr     string
+	}{
+		{
+			name:        "everything revoked",
+			credentials: config.Credentials{AccessToken: "access", RefreshToken: "refresh", SessionID: "sid"},
+			revoke:      http.StatusOK,
+			session:     http.StatusNoContent,
+			want:        []string{"DELETE session sid", "revoke access_token access", "revoke refresh_token refresh"},
+		},
+		{
+			name:        "no refresh token or session",
+			credentials: config.Credentials{AccessToken: "access"},
+			revoke:      http.StatusOK,
+			want:        []string{"revoke access_token access"},
+		},
+		{
+			name:        "session already expired",
+			credentials: config.Credentials{SessionID: "sid"},
+			session:     http.StatusNotFound,
+			want:        []string{"DELETE session sid"},
+		},
+		{
+			name:        "failures don't stop the rest",
+			credentials: config.Credentials{AccessToken: "access", RefreshToken: "refresh", SessionID: "sid"},
+			revoke:      http.StatusBadRequest,
+			session:     http.StatusForbidden,
+			want:        []string{"DELETE session sid", "revoke access_token access", "revoke refresh_token refresh"},
+			wantErr:     "logout incomplete: access token: revoke returned HTTP 400; refresh token: revoke returned HTTP 400; Okta session: session delete returned HTTP 403",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := []string{}
+			c := testConfig(t, func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/oauth2/default/v1/revoke":
+					r.ParseForm()
+					if r.PostForm.Get("client_id") != "client" {
+						t.Errorf("client_id = %q", r.PostForm.Get("client_id"))
+					}
+					requests = append(requests, "revoke "+r.PostForm.Get("token_type_hint")+" "+r.PostForm.Get("token"))
+					w.WriteHeader(tt.revoke)
+				case "/api/v1/sessions/me":
+					cookie, _ := r.Cookie("sid")
+					requests = append(requests, r.Method+" session "+cookie.Value)
+					w.WriteHeader(tt.session)
+				default:
+					t.Errorf("unexpected request to %v", r.URL.Path)
+				}
+			})
+
+			err := Logout(c, &tt.credentials)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Logout() error = %v", err)
+			} else if tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr) {
+				t.Errorf("Logout() error = %v, want %q", err, tt.wantErr)
+			}
+
+			sort.Strings(requests)
+			if strings.Join(requests, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("requests = %q, want %q", requests, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"net/url"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// Refresh exchanges a refresh token for a new access token without prompting. Unlike Auth,
+// it returns errors rather than exiting or printing, so it can be used by long-running processes
+func Refresh(c config.Configuration, refreshToken string) (*config.Credentials, error) {
+	r, err := oauthToken(c, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+	}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	p := new(config.Credentials)
+	p.ExpiresIn = r.ExpiresIn
+	p.ExpiresAt = expiresAt(r.AccessToken, r.ExpiresIn)
+	p.AccessToken = r.AccessToken
+	p.RefreshToken = r.RefreshToken
+
+	// Without refresh token rotation, the same refresh token stays valid
+	if p.RefreshToken == "" {
+		p.RefreshToken = refreshToken
+	}
+
+	return p, nil
+}
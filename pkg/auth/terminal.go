@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// promptStdin is the terminal that prompts read from once `--password-stdin` has used up stdin
+var promptStdin io.ReadCloser
+
+// reopenTerminal points later prompts, such as MFA, at the controlling terminal after the
+// password has been read from stdin
+func reopenTerminal(c config.Configuration) {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		c.Logger.Debug("No terminal to prompt on", "error", err)
+		return
+	}
+
+	// Prompts put os.Stdin into raw mode, so it has to be the terminal as well
+	os.Stdin = tty
+	promptStdin = tty
+}
+
+// canPrompt reports an error when stdin was used for the password and there's no terminal to prompt on instead
+func canPrompt(c config.Configuration) error {
+	if c.PasswordStdin && promptStdin == nil {
+		fmt.Println(string(c.ColorFailure), "MFA needs a terminal to prompt on: --password-stdin can't be used without one")
+		return errors.New("no terminal for MFA prompt")
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+var airflowIDChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// airflowConnection is a Snowflake connection in the format read by
+// `airflow connections import` and AIRFLOW_CONN_* variables
+type airflowConnection struct {
+	ConnType string            `json:"conn_type" yaml:"conn_type"`
+	Login    string            `json:"login" yaml:"login"`
+	Schema   string            `json:"schema" yaml:"schema"`
+	Extra    map[string]string `json:"extra" yaml:"extra"`
+}
+
+// airflowWriter exports the profile as an Airflow connection, both as an AIRFLOW_CONN_*
+// variable in a per-profile `.env` and as an entry in a connections import file
+type airflowWriter struct{}
+
+func (w *airflowWriter) Name() string {
+	return "airflow"
+}
+
+func (w *airflowWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *airflowWriter) connID(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "id", "snowflake_"+airflowIDChars.ReplaceAllString(c.ProfileName, "_"))
+}
+
+func (w *airflowWriter) envName(c config.Configuration) string {
+	return "AIRFLOW_CONN_" + strings.ToUpper(w.connID(c))
+}
+
+func (w *airflowWriter) envPath(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "env-path", c.HomeDir+"/.gsc/"+c.ProfileName+"/.env")
+}
+
+func (w *airflowWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "path", c.HomeDir+"/.gsc/"+c.ProfileName+"/airflow-connections.json")
+}
+
+func (w *airflowWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var connectionsFile = w.path(c)
+
+	connections, err := loadAirflowConnections(connectionsFile)
+	if err == nil {
+		connections[w.connID(c)] = airflowConnectionFor(c, t)
+		err = saveAirflowConnections(c, connectionsFile, connections)
+	}
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Airflow: Couldn't write connections!")
+		c.Logger.Debug("Couldn't write Airflow connections", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "Airflow: Connection", w.connID(c), "written to:", connectionsFile)
+
+	if !c.Profile.OutputFlag(w.Name(), "env", true) {
+		return nil
+	}
+
+	var envFile = w.envPath(c)
+
+	err = upsertEnvFile(c, envFile, w.Env(c, t))
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Airflow: Couldn't write `.env`!")
+		c.Logger.Debug("Couldn't write Airflow `.env`", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "Airflow: Profile", c.ProfileName, "written to:", envFile)
+
+	return nil
+}
+
+func (w *airflowWriter) Remove(ctx context.Context, c config.Configuration) error {
+	var connectionsFile = w.path(c)
+
+	connections, err := loadAirflowConnections(connectionsFile)
+	if err == nil {
+		if _, ok := connections[w.connID(c)]; ok {
+			delete(connections, w.connID(c))
+			if len(connections) == 0 {
+				err = os.Remove(connectionsFile)
+			} else {
+				err = saveAirflowConnections(c, connectionsFile, connections)
+			}
+
+			if err == nil {
+				fmt.Println(string(c.ColorSuccess), "Airflow: Connection", w.connID(c), "removed from:", connectionsFile)
+			}
+		}
+	}
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Airflow: Couldn't write connections!")
+		c.Logger.Debug("Couldn't write Airflow connections", "error", err)
+		return err
+	}
+
+	var envFile = w.envPath(c)
+
+	removed, err := removeEnvVars(c, envFile, []string{w.envName(c)})
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Airflow: Couldn't write `.env`!")
+		c.Logger.Debug("Couldn't write Airflow `.env`", "error", err)
+		return err
+	}
+
+	if removed {
+		fmt.Println(string(c.ColorSuccess), "Airflow: Profile", c.ProfileName, "removed from:", envFile)
+	}
+
+	return nil
+}
+
+// Env renders the connection as JSON, or as a URI with the `format: uri` option for
+// Airflow releases before 2.3
+func (w *airflowWriter) Env(c config.Configuration, t *config.Credentials) [][2]string {
+	connection := airflowConnectionFor(c, t)
+
+	if c.Profile.OutputOption(w.Name(), "format", "json") == "uri" {
+		return [][2]string{{w.envName(c), airflowURI(connection)}}
+	}
+
+	out, _ := json.Marshal(connection)
+
+	return [][2]string{{w.envName(c), string(out)}}
+}
+
+func airflowConnectionFor(c config.Configuration, t *config.Credentials) airflowConnection {
+	extra := map[string]string{
+		"account":   c.Profile.Account,
+		"warehouse": c.Profile.Warehouse,
+		"database":  c.Profile.Database,
+		"role":      c.Profile.Role,
+	}
+
+	// SnowflakeHook ignores the token, and fetches its own with a client secret and
+	// refresh token instead, so it's only used by tasks that connect with the extras
+	if c.Profile.OAuth {
+		extra["authenticator"] = "oauth"
+		extra["token"] = t.AccessToken
+	} else {
+		extra["authenticator"] = "externalbrowser"
+	}
+
+	return airflowConnection{
+		ConnType: "snowflake",
+		Login:    c.Profile.Username,
+		Schema:   c.Profile.Schema,
+		Extra:    extra,
+	}
+}
+
+// airflowURI renders a connection in Airflow's URI format, where extras are query parameters
+func airflowURI(connection airflowConnection) string {
+	query := url.Values{}
+	for key, value := range connection.Extra {
+		query.Set(key, value)
+	}
+
+	u := url.URL{
+		Scheme:   connection.ConnType,
+		User:     url.User(connection.Login),
+		Path:     "/" + connection.Schema,
+		RawPath:  "/" + url.PathEscape(connection.Schema),
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
+
+// isYAML reports whether a connections file is YAML rather than JSON, going by its extension
+func isYAML(path string) bool {
+	extension := strings.ToLower(filepath.Ext(path))
+	return extension == ".yaml" || extension == ".yml"
+}
+
+// loadAirflowConnections reads an `airflow connections import` file, keeping any other
+// connections in it
+func loadAirflowConnections(path string) (map[string]interface{}, error) {
+	connections := map[string]interface{}{}
+
+	in, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return connections, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if isYAML(path) {
+		err = yaml.Unmarshal(in, &connections)
+	} else if len(strings.TrimSpace(string(in))) > 0 {
+		err = json.Unmarshal(in, &connections)
+	}
+
+	return connections, err
+}
+
+func saveAirflowConnections(c config.Configuration, path string, connections map[string]interface{}) error {
+	err := ensureDir(c, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	var out []byte
+	if isYAML(path) {
+		out, err = yaml.Marshal(connections)
+	} else {
+		out, err = json.MarshalIndent(connections, "", "  ")
+		out = append(out, '\n')
+	}
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, out, fileMode)
+}
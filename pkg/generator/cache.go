@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"os"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/internal/logging"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/secrets"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+	"gopkg.in/ini.v1"
+)
+
+// tokenCachePath is where a profile's tokens are cached for `exec`, `token`, `logout` and Go
+// programs, whichever outputs the profile selects
+func tokenCachePath(c config.Configuration) string {
+	return c.HomeDir + "/.gsc/" + c.ProfileName + "/token"
+}
+
+// WriteTokenCache caches the profile's tokens without printing anything. The refresh token
+// and Okta session go to the secret store, and only fall back to the cache when it's read-only
+func WriteTokenCache(c config.Configuration, t *config.Credentials) error {
+	var cacheFile = tokenCachePath(c)
+
+	err := ensureDir(c, c.HomeDir+"/.gsc/"+c.ProfileName)
+	if err != nil {
+		return err
+	}
+
+	values := [][2]string{{"ACCESS_TOKEN", t.AccessToken}}
+	if !secrets.SaveTokens(c, t) {
+		values = append(values, [2]string{"REFRESH_TOKEN", t.RefreshToken}, [2]string{"SESSION_ID", t.SessionID})
+	}
+
+	cache := ini.Empty()
+	for _, value := range values {
+		if value[1] == "" {
+			continue
+		}
+
+		// Cached tokens are encrypted along with the generic output
+		secret := value[1]
+		if c.Profile.EncryptToken {
+			secret, err = vault.Open(c).Encrypt(secret)
+			if err != nil {
+				c.Logger.Debug("Couldn't encrypt token", "error", err)
+				return err
+			}
+		}
+
+		cache.Section("").Key(value[0]).SetValue(secret)
+	}
+
+	if !t.ExpiresAt.IsZero() {
+		cache.Section("").Key("EXPIRES_AT").SetValue(t.ExpiresAt.Format(time.RFC3339))
+	}
+
+	return saveINI(c, cache, cacheFile)
+}
+
+// ReadTokenCache returns the profile's cached tokens
+func ReadTokenCache(c config.Configuration) (*config.Credentials, error) {
+	t := new(config.Credentials)
+
+	cache, err := ini.Load(tokenCachePath(c))
+	if err != nil {
+		c.Logger.Debug("Couldn't read token cache", "error", err)
+		secrets.LoadTokens(c, t)
+		return t, err
+	}
+
+	t.AccessToken = cache.Section("").Key("ACCESS_TOKEN").String()
+	t.RefreshToken = cache.Section("").Key("REFRESH_TOKEN").String()
+	t.SessionID = cache.Section("").Key("SESSION_ID").String()
+	t.ExpiresAt, _ = time.Parse(time.RFC3339, cache.Section("").Key("EXPIRES_AT").String())
+
+	for _, value := range []*string{&t.AccessToken, &t.RefreshToken, &t.SessionID} {
+		if vault.IsEncrypted(*value) {
+			*value, err = vault.Open(c).Decrypt(*value)
+			if err != nil {
+				c.Logger.Debug("Couldn't decrypt token", "error", err)
+				return t, err
+			}
+		}
+		logging.Redact(*value)
+	}
+
+	secrets.LoadTokens(c, t)
+	logging.Redact(t.RefreshToken)
+	logging.Redact(t.SessionID)
+
+	return t, nil
+}
+
+// RemoveTokenCache deletes the profile's cached tokens, including any in the secret store
+func RemoveTokenCache(c config.Configuration) error {
+	err := secrets.DeleteTokens(c)
+	if err != nil {
+		c.Logger.Debug("Couldn't remove tokens from secret store", "error", err)
+	}
+
+	err = os.Remove(tokenCachePath(c))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// connectionsWriter upserts a `[<profile>]` table into the `connections.toml` shared by
+// the Snowflake CLI and the Python connector
+type connectionsWriter struct{}
+
+func (w *connectionsWriter) Name() string {
+	return "connections"
+}
+
+func (w *connectionsWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+// dir honours SNOWFLAKE_HOME, as the Snowflake clients do
+func (w *connectionsWriter) dir(c config.Configuration) string {
+	dir := c.HomeDir + "/.snowflake"
+	if home := os.Getenv("SNOWFLAKE_HOME"); home != "" {
+		dir = home
+	}
+
+	return c.Profile.OutputOption(w.Name(), "dir", dir)
+}
+
+func (w *connectionsWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var connectionsDir = w.dir(c)
+	var connectionsFile = connectionsDir + "/connections.toml"
+
+	err := ensureDir(c, connectionsDir)
+	if err != nil {
+		c.Logger.Debug("Couldn't create Snowflake configuration directory", "error", err)
+		return err
+	}
+
+	if _, err := os.Stat(connectionsFile); os.IsNotExist(err) {
+		fmt.Println(string(c.ColorSuccess), "Connections: No existing `connections.toml`, creating file...")
+	}
+
+	// Only the profile's table is edited
+	deleted := []string{}
+	if !c.Profile.OAuth {
+		deleted = append(deleted, "token")
+	}
+
+	err = upsertTOML(c, connectionsFile, []string{c.ProfileName}, snowflakeConnection(c, t), deleted...)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Connections: Couldn't write `connections.toml`!")
+		c.Logger.Debug("Couldn't write `connections.toml`", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "Connections: Profile", c.ProfileName, "written to:", connectionsFile)
+
+	// The default connection is read from `config.toml` alongside `connections.toml`
+	if c.Profile.OutputFlag(w.Name(), "default", false) {
+		var configFile = connectionsDir + "/config.toml"
+
+		err = upsertTOML(c, configFile, nil, map[string]interface{}{"default_connection_name": c.ProfileName})
+		if err != nil {
+			fmt.Println(string(c.ColorFailure), "Connections: Couldn't write `config.toml`!")
+			c.Logger.Debug("Couldn't write `config.toml`", "error", err)
+			return err
+		}
+
+		fmt.Println(string(c.ColorSuccess), "Connections: Profile", c.ProfileName, "set as default connection in:", configFile)
+	}
+
+	return nil
+}
+
+func (w *connectionsWriter) Remove(ctx context.Context, c config.Configuration) error {
+	var connectionsFile = w.dir(c) + "/connections.toml"
+
+	removed, err := removeTOMLKey(c, connectionsFile, []string{c.ProfileName}, "token")
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Connections: Couldn't write `connections.toml`!")
+		c.Logger.Debug("Couldn't write `connections.toml`", "error", err)
+		return err
+	}
+
+	if removed {
+		fmt.Println(string(c.ColorSuccess), "Connections: Token for profile", c.ProfileName, "removed from:", connectionsFile)
+	}
+
+	return nil
+}
+
+// snowflakeConnection is a connection in the format shared by the Snowflake CLI, the
+// Python connector and Streamlit
+func snowflakeConnection(c config.Configuration, t *config.Credentials) map[string]interface{} {
+	connection := map[string]interface{}{
+		"account":   c.Profile.Account,
+		"user":      c.Profile.Username,
+		"role":      c.Profile.Role,
+		"warehouse": c.Profile.Warehouse,
+		"database":  c.Profile.Database,
+		"schema":    c.Profile.Schema,
+	}
+
+	if c.Profile.OAuth {
+		connection["authenticator"] = "oauth"
+		connection["token"] = t.AccessToken
+	} else {
+		connection["authenticator"] = "externalbrowser"
+	}
+
+	return connection
+}
@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+var kubernetesNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// kubernetesWriter writes the profile's connection as a Kubernetes `Secret` manifest,
+// ready for `kubectl apply -f`
+type kubernetesWriter struct{}
+
+func (w *kubernetesWriter) Name() string {
+	return "kubernetes"
+}
+
+func (w *kubernetesWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *kubernetesWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "path", c.HomeDir+"/.gsc/"+c.ProfileName+"/secret.yaml")
+}
+
+// secretName defaults to the profile name, made a valid Kubernetes resource name
+func (w *kubernetesWriter) secretName(c config.Configuration) string {
+	name := strings.Trim(kubernetesNameChars.ReplaceAllString(strings.ToLower(c.ProfileName), "-"), "-")
+	return c.Profile.OutputOption(w.Name(), "name", "snowflake-"+name)
+}
+
+func (w *kubernetesWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var secretFile = w.path(c)
+
+	metadata := yaml.MapSlice{{Key: "name", Value: w.secretName(c)}}
+	if namespace := c.Profile.OutputOption(w.Name(), "namespace", ""); namespace != "" {
+		metadata = append(metadata, yaml.MapItem{Key: "namespace", Value: namespace})
+	}
+
+	// Keys default to the variable names, so the Secret can be used with `envFrom`;
+	// each can be renamed with a `key-<setting>` option, such as `key-token`
+	data := yaml.MapSlice{}
+	for _, v := range snowflakeEnv(c, t) {
+		option := "key-" + strings.ToLower(strings.TrimPrefix(v[0], "SNOWFLAKE_"))
+		data = append(data, yaml.MapItem{Key: c.Profile.OutputOption(w.Name(), option, v[0]), Value: v[1]})
+	}
+
+	secret := yaml.MapSlice{
+		{Key: "apiVersion", Value: "v1"},
+		{Key: "kind", Value: "Secret"},
+		{Key: "metadata", Value: metadata},
+		{Key: "type", Value: "Opaque"},
+		{Key: "stringData", Value: data},
+	}
+
+	out, err := yaml.Marshal(secret)
+	if err == nil {
+		err = writeSecretFile(c, secretFile, "# Generated by gimme-snowflake-creds for profile "+c.ProfileName+"\n"+strings.TrimRight(string(out), "\n"))
+	}
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Kubernetes: Couldn't write Secret!")
+		c.Logger.Debug("Couldn't write Kubernetes Secret", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "Kubernetes: Profile", c.ProfileName, "written to:", secretFile)
+
+	return nil
+}
+
+func (w *kubernetesWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return removeFile(c, "Kubernetes", w.path(c))
+}
+
+// dockerWriter writes the profile's connection as a file for `docker run --env-file`
+// and the `env_file` of docker-compose
+type dockerWriter struct{}
+
+func (w *dockerWriter) Name() string {
+	return "docker"
+}
+
+func (w *dockerWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *dockerWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "path", c.HomeDir+"/.gsc/"+c.ProfileName+"/docker.env")
+}
+
+func (w *dockerWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var envFile = w.path(c)
+
+	// Docker takes values literally, so they aren't quoted
+	var b strings.Builder
+	b.WriteString("# Generated by gimme-snowflake-creds for profile " + c.ProfileName)
+	for _, v := range snowflakeEnv(c, t) {
+		b.WriteString("\n" + v[0] + "=" + v[1])
+	}
+
+	err := writeSecretFile(c, envFile, b.String())
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Docker: Couldn't write env-file!")
+		c.Logger.Debug("Couldn't write Docker env-file", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "Docker: Profile", c.ProfileName, "written to:", envFile)
+
+	return nil
+}
+
+func (w *dockerWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return removeFile(c, "Docker", w.path(c))
+}
@@ -0,0 +1,157 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// dbeaverWriter upserts a Snowflake connection, keyed by profile, into DBeaver's `data-sources.json`
+type dbeaverWriter struct{}
+
+func (w *dbeaverWriter) Name() string {
+	return "dbeaver"
+}
+
+func (w *dbeaverWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+// path defaults to the General project of DBeaver's default workspace
+func (w *dbeaverWriter) path(c config.Configuration) string {
+	workspace := c.HomeDir + "/.local/share/DBeaverData/workspace6"
+	if runtime.GOOS == "darwin" {
+		workspace = c.HomeDir + "/Library/DBeaverData/workspace6"
+	}
+
+	return c.Profile.OutputOption(w.Name(), "path", workspace+"/General/.dbeaver/data-sources.json")
+}
+
+func (w *dbeaverWriter) connectionID(c config.Configuration) string {
+	return "gsc-" + c.ProfileName
+}
+
+func (w *dbeaverWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var dataSourcesFile = w.path(c)
+
+	dataSources, err := loadJSON(dataSourcesFile)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "DBeaver: Couldn't read `data-sources.json`!")
+		c.Logger.Debug("Couldn't read DBeaver data sources", "error", err)
+		return err
+	}
+
+	properties := map[string]interface{}{"authenticator": "externalbrowser"}
+	if c.Profile.OAuth {
+		properties = map[string]interface{}{"authenticator": "oauth", "token": t.AccessToken}
+	}
+
+	// Settings made in DBeaver, such as folders and colours, are kept
+	connections := jsonObject(dataSources, "connections")
+	connection := jsonObject(connections, w.connectionID(c))
+	connection["provider"] = "snowflake"
+	connection["driver"] = "snowflake"
+	connection["name"] = c.ProfileName
+
+	configuration := jsonObject(connection, "configuration")
+	configuration["host"] = snowflakeHost(c)
+	configuration["database"] = c.Profile.Database
+	configuration["type"] = "dev"
+	configuration["auth-model"] = "native"
+	configuration["user"] = c.Profile.Username
+	configuration["properties"] = properties
+
+	providerProperties := jsonObject(configuration, "provider-properties")
+	providerProperties["@dbeaver-warehouse@"] = c.Profile.Warehouse
+	providerProperties["@dbeaver-role@"] = c.Profile.Role
+	providerProperties["@dbeaver-schema@"] = c.Profile.Schema
+
+	err = saveJSON(c, dataSourcesFile, dataSources)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "DBeaver: Couldn't write `data-sources.json`!")
+		c.Logger.Debug("Couldn't write DBeaver data sources", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "DBeaver: Profile", c.ProfileName, "written to:", dataSourcesFile)
+
+	return nil
+}
+
+func (w *dbeaverWriter) Remove(ctx context.Context, c config.Configuration) error {
+	var dataSourcesFile = w.path(c)
+
+	dataSources, err := loadJSON(dataSourcesFile)
+	if err != nil {
+		c.Logger.Debug("Couldn't read DBeaver data sources", "error", err)
+		return nil
+	}
+
+	properties := jsonObject(jsonObject(jsonObject(jsonObject(dataSources, "connections"), w.connectionID(c)), "configuration"), "properties")
+	if _, ok := properties["token"]; !ok {
+		return nil
+	}
+	delete(properties, "token")
+
+	err = saveJSON(c, dataSourcesFile, dataSources)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "DBeaver: Couldn't write `data-sources.json`!")
+		c.Logger.Debug("Couldn't write DBeaver data sources", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "DBeaver: Token for profile", c.ProfileName, "removed from:", dataSourcesFile)
+
+	return nil
+}
+
+// loadJSON reads a JSON object, returning an empty one when the file doesn't exist
+func loadJSON(path string) (map[string]interface{}, error) {
+	document := map[string]interface{}{}
+
+	in, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return document, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return document, json.Unmarshal(in, &document)
+}
+
+// saveJSON writes a token-bearing JSON file that only the current user can read
+func saveJSON(c config.Configuration, path string, document interface{}) error {
+	err := ensureDir(c, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	out, err := json.MarshalIndent(document, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(out, '\n'), fileMode)
+}
+
+// jsonObject returns the object under key, adding an empty one if it's missing
+func jsonObject(parent map[string]interface{}, key string) map[string]interface{} {
+	child, ok := parent[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		parent[key] = child
+	}
+
+	return child
+}
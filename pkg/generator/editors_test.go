@@ -0,0 +1,231 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUpsertYAML(t *testing.T) {
+	settings := [][2]string{{"snowflake-user", "me"}, {"snowflake-role", "yes"}, {"snowflake-token-path", "/tmp/a: b"}}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+		err  bool
+	}{
+		{
+			name: "new file",
+			want: "snowflake-user: me\nsnowflake-role: \"yes\"\nsnowflake-token-path: '/tmp/a: b'\n",
+		},
+		{
+			name: "comments and order kept",
+			in:   "# schemachange\nroot-folder: migrations # keep\nsnowflake-user: old\nvars:\n  env: dev\n\n# role\nsnowflake-role:\n  - a\n  - b\ncreate-change-history-table: true\n",
+			want: "# schemachange\nroot-folder: migrations # keep\nsnowflake-user: me\nvars:\n  env: dev\n\n# role\nsnowflake-role: \"yes\"\ncreate-change-history-table: true\nsnowflake-token-path: '/tmp/a: b'\n",
+		},
+		{
+			name: "quoted and repeated keys",
+			in:   "\"snowflake-user\": old\nsnowflake-user: older\n",
+			want: "snowflake-user: me\nsnowflake-role: \"yes\"\nsnowflake-token-path: '/tmp/a: b'\n",
+		},
+		{
+			name: "not a mapping",
+			in:   "- snowflake-user: old\n",
+			err:  true,
+		},
+		{
+			name: "invalid",
+			in:   "snowflake-user: [old\n",
+			err:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yml")
+			if tt.in != "" {
+				writeTestFile(t, path, tt.in)
+			}
+
+			err := upsertYAML(testConfig(t), path, settings)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("upsertYAML() error = nil")
+				}
+				if got := readTestFile(t, path); got != tt.in {
+					t.Errorf("file changed on error:\n%s", got)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("upsertYAML() error = %v", err)
+			}
+
+			if got := readTestFile(t, path); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertProperties(t *testing.T) {
+	properties := [][2]string{{"flyway.url", `jdbc:snowflake://host/?a=1\b`}, {"flyway.user", "me\nyou"}}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "new file",
+			want: "flyway.url=jdbc:snowflake://host/?a=1\\\\b\nflyway.user=me\\nyou\n",
+		},
+		{
+			name: "other settings kept",
+			in:   "# Flyway\nflyway.locations=filesystem:sql\n  flyway.url = old\n! note\n\nflyway.urls=other\n",
+			want: "# Flyway\nflyway.locations=filesystem:sql\nflyway.url=jdbc:snowflake://host/?a=1\\\\b\n! note\n\nflyway.urls=other\nflyway.user=me\\nyou\n",
+		},
+		{
+			name: "blank file",
+			in:   "\n\n",
+			want: "flyway.url=jdbc:snowflake://host/?a=1\\\\b\nflyway.user=me\\nyou\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "flyway.conf")
+			if tt.in != "" {
+				writeTestFile(t, path, tt.in)
+			}
+
+			if err := upsertProperties(testConfig(t), path, properties); err != nil {
+				t.Fatalf("upsertProperties() error = %v", err)
+			}
+
+			if got := readTestFile(t, path); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveProperties(t *testing.T) {
+	c := testConfig(t)
+	path := filepath.Join(c.HomeDir, "liquibase.properties")
+	keys := []string{"url", "username"}
+
+	if removed, err := removeProperties(c, path, keys); removed || err != nil {
+		t.Errorf("removeProperties() of a missing file = %v, %v", removed, err)
+	}
+
+	in := "# Liquibase\nchangeLogFile=changelog.xml\nurl=jdbc:snowflake://host/?token=t\nusername: me\n"
+	writeTestFile(t, path, in)
+
+	removed, err := removeProperties(c, path, keys)
+	if !removed || err != nil {
+		t.Fatalf("removeProperties() = %v, %v", removed, err)
+	}
+	want := "# Liquibase\nchangeLogFile=changelog.xml\n"
+	if got := readTestFile(t, path); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Nothing to remove leaves the file alone
+	if removed, err := removeProperties(c, path, keys); removed || err != nil {
+		t.Errorf("removeProperties() again = %v, %v", removed, err)
+	}
+	if got := readTestFile(t, path); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUpsertEnvFile(t *testing.T) {
+	vars := [][2]string{{"SNOWFLAKE_TOKEN", "new"}, {"SNOWFLAKE_URL", "it's"}}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "new file",
+			want: "SNOWFLAKE_TOKEN='new'\nSNOWFLAKE_URL='it'\\''s'\n",
+		},
+		{
+			name: "other lines kept",
+			in:   "# app\nexport FOO=1\n  export SNOWFLAKE_TOKEN=old\n\nSNOWFLAKE_TOKENS='x'\n\n",
+			want: "# app\nexport FOO=1\nSNOWFLAKE_TOKEN='new'\n\nSNOWFLAKE_TOKENS='x'\nSNOWFLAKE_URL='it'\\''s'\n",
+		},
+		{
+			name: "blank file",
+			in:   "\n",
+			want: "SNOWFLAKE_TOKEN='new'\nSNOWFLAKE_URL='it'\\''s'\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".env")
+			if tt.in != "" {
+				writeTestFile(t, path, tt.in)
+			}
+
+			if err := upsertEnvFile(testConfig(t), path, vars); err != nil {
+				t.Fatalf("upsertEnvFile() error = %v", err)
+			}
+
+			if got := readTestFile(t, path); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveEnvVars(t *testing.T) {
+	c := testConfig(t)
+	path := filepath.Join(c.HomeDir, ".env")
+	names := []string{"SNOWFLAKE_TOKEN", "SNOWFLAKE_URL"}
+
+	if removed, err := removeEnvVars(c, path, names); removed || err != nil {
+		t.Errorf("removeEnvVars() of a missing file = %v, %v", removed, err)
+	}
+
+	writeTestFile(t, path, "# app\nexport SNOWFLAKE_TOKEN='t'\nSNOWFLAKE_TOKENS=1\nSNOWFLAKE_URL='u'\n")
+
+	removed, err := removeEnvVars(c, path, names)
+	if !removed || err != nil {
+		t.Fatalf("removeEnvVars() = %v, %v", removed, err)
+	}
+	want := "# app\nSNOWFLAKE_TOKENS=1\n"
+	if got := readTestFile(t, path); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// A file left with nothing in it is removed
+	writeTestFile(t, path, "SNOWFLAKE_TOKEN='t'\n")
+	if removed, err := removeEnvVars(c, path, names); !removed || err != nil {
+		t.Fatalf("removeEnvVars() = %v, %v", removed, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("emptied file wasn't removed: %v", err)
+	}
+}
+
+func TestWriteShellEnv(t *testing.T) {
+	c := testConfig(t)
+	path := filepath.Join(c.HomeDir, ".gsc", "dev", "terraform.env")
+
+	// The file is replaced rather than edited
+	if err := writeShellEnv(c, path, [][2]string{{"OLD", "x"}}); err != nil {
+		t.Fatalf("writeShellEnv() error = %v", err)
+	}
+	if err := writeShellEnv(c, path, [][2]string{{"SNOWFLAKE_USER", "me"}, {"SNOWFLAKE_TOKEN", "it's"}}); err != nil {
+		t.Fatalf("writeShellEnv() error = %v", err)
+	}
+
+	want := "# Generated by gimme-snowflake-creds for profile dev\nexport SNOWFLAKE_USER='me'\nexport SNOWFLAKE_TOKEN='it'\\''s'\n"
+	if got := readTestFile(t, path); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
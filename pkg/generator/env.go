@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/utils"
+)
+
+// snowflakeEnv is the profile's connection as the SNOWFLAKE_* variables read by the
+// Snowflake clients and most tools built on them
+func snowflakeEnv(c config.Configuration, t *config.Credentials) [][2]string {
+	vars := [][2]string{
+		{"SNOWFLAKE_ACCOUNT", c.Profile.Account},
+		{"SNOWFLAKE_USER", c.Profile.Username},
+		{"SNOWFLAKE_ROLE", c.Profile.Role},
+		{"SNOWFLAKE_WAREHOUSE", c.Profile.Warehouse},
+		{"SNOWFLAKE_DATABASE", c.Profile.Database},
+		{"SNOWFLAKE_SCHEMA", c.Profile.Schema},
+	}
+
+	if c.Profile.OAuth {
+		vars = append(vars, [2]string{"SNOWFLAKE_AUTHENTICATOR", "oauth"}, [2]string{"SNOWFLAKE_TOKEN", t.AccessToken})
+	} else {
+		vars = append(vars, [2]string{"SNOWFLAKE_AUTHENTICATOR", "externalbrowser"})
+	}
+
+	return vars
+}
+
+// quoteEnv single-quotes a value for `.env` files and shells
+func quoteEnv(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// upsertEnvFile sets variables in a `.env`-style file that only the current user can read,
+// keeping any other lines as they are
+func upsertEnvFile(c config.Configuration, path string, vars [][2]string) error {
+	err := ensureDir(c, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{}
+	existing, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	} else if len(strings.TrimSpace(string(existing))) > 0 {
+		lines = strings.Split(strings.TrimRight(string(existing), "\n"), "\n")
+	}
+
+	for _, v := range vars {
+		line := v[0] + "=" + quoteEnv(v[1])
+
+		replaced := false
+		for i, existing := range lines {
+			name := strings.TrimPrefix(strings.TrimSpace(existing), "export ")
+			if strings.HasPrefix(name, v[0]+"=") {
+				lines[i] = line
+				replaced = true
+			}
+		}
+		if !replaced {
+			lines = append(lines, line)
+		}
+	}
+
+	return ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), fileMode)
+}
+
+// removeEnvVars deletes variables from a `.env`-style file, reporting whether any were found
+func removeEnvVars(c config.Configuration, path string, names []string) (bool, error) {
+	existing, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	removed := false
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimRight(string(existing), "\n"), "\n") {
+		name := strings.TrimPrefix(strings.TrimSpace(line), "export ")
+		if i := strings.Index(name, "="); i > 0 && utils.Contains(names, name[:i]) {
+			removed = true
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if !removed {
+		return false, nil
+	} else if len(lines) == 0 {
+		return true, os.Remove(path)
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return false, err
+	}
+
+	return true, ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), fileMode)
+}
+
+// writeShellEnv writes a file of `export` statements that only the current user can read,
+// for sourcing into a shell
+func writeShellEnv(c config.Configuration, path string, vars [][2]string) error {
+	err := ensureDir(c, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by gimme-snowflake-creds for profile " + c.ProfileName + "\n")
+	for _, v := range vars {
+		b.WriteString("export " + v[0] + "=" + quoteEnv(v[1]) + "\n")
+	}
+
+	return ioutil.WriteFile(path, []byte(b.String()), fileMode)
+}
@@ -3,8 +3,12 @@ package generator
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/internal/logging"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
 	"github.com/spf13/viper"
 	"gopkg.in/ini.v1"
 )
@@ -15,9 +19,10 @@ func WriteGenericCredentials(c config.Configuration, t *config.Credentials) erro
 	var genericAuthUri = "authenticator=oauth&token=" + t.AccessToken
 
 	// Ensure `~/.gsc` directory exists
-	if _, err := os.Stat(genericConfigPath); os.IsNotExist(err) {
-		c.Logger.Debug("Couldn't find existing generic configuration path, creating...", "error", err)
-		os.MkdirAll(genericConfigPath, os.ModePerm)
+	err := ensureDir(c, genericConfigPath)
+	if err != nil {
+		c.Logger.Debug("Couldn't create generic configuration path", "error", err)
+		return err
 	}
 
 	generic, err := ini.Load(genericConfigFile)
@@ -27,12 +32,35 @@ func WriteGenericCredentials(c config.Configuration, t *config.Credentials) erro
 		generic = ini.Empty()
 	}
 
+	// Tokens encrypted at rest are fetched with `gimme-snowflake-creds token` at use time
+	accessToken := t.AccessToken
+	if c.Profile.EncryptToken && accessToken != "" {
+		accessToken, err = vault.Open(c).Encrypt(accessToken)
+		if err != nil {
+			fmt.Println(string(c.ColorFailure), "Generic: Couldn't encrypt token!")
+			c.Logger.Debug("Couldn't encrypt token", "error", err)
+			return err
+		}
+	}
+
 	generic.Section("").Key("SNOWFLAKE_USER").SetValue(c.Profile.Username)
 	generic.Section("").Key("SNOWFLAKE_UID").SetValue(c.Profile.Username)
-	generic.Section("").Key("SNOWFLAKE_OAUTH_ACCESS_TOKEN").SetValue(t.AccessToken)
-	generic.Section("").Key("SNOWFLAKE_AUTH_URI").SetValue(genericAuthUri)
+	generic.Section("").Key("SNOWFLAKE_OAUTH_ACCESS_TOKEN").SetValue(accessToken)
+	if c.Profile.EncryptToken {
+		generic.Section("").DeleteKey("SNOWFLAKE_AUTH_URI")
+	} else {
+		generic.Section("").Key("SNOWFLAKE_AUTH_URI").SetValue(genericAuthUri)
+	}
 
-	err = generic.SaveTo(genericConfigFile)
+	if !t.ExpiresAt.IsZero() {
+		generic.Section("").Key("SNOWFLAKE_OAUTH_EXPIRES_AT").SetValue(t.ExpiresAt.Format(time.RFC3339))
+	}
+
+	// Earlier releases kept these here, they're now in the token cache
+	generic.Section("").DeleteKey("SNOWFLAKE_OAUTH_REFRESH_TOKEN")
+	generic.Section("").DeleteKey("OKTA_SESSION_ID")
+
+	err = saveINI(c, generic, genericConfigFile)
 	if err != nil {
 		fmt.Println(string(c.ColorFailure), "Generic: Couldn't write config!")
 		c.Logger.Debug("Couldn't write generic config", "error", err)
@@ -44,15 +72,91 @@ func WriteGenericCredentials(c config.Configuration, t *config.Credentials) erro
 	return nil
 }
 
+func ReadGenericCredentials(c config.Configuration) (*config.Credentials, error) {
+	var genericConfigFile = c.HomeDir + "/.gsc/" + c.ProfileName + "/credentials"
+
+	t := new(config.Credentials)
+
+	generic, err := ini.Load(genericConfigFile)
+	if err != nil {
+		c.Logger.Debug("Couldn't read existing generic config", "error", err)
+		return t, err
+	}
+
+	t.AccessToken = generic.Section("").Key("SNOWFLAKE_OAUTH_ACCESS_TOKEN").String()
+	t.ExpiresAt, _ = time.Parse(time.RFC3339, generic.Section("").Key("SNOWFLAKE_OAUTH_EXPIRES_AT").String())
+	t.RefreshToken = generic.Section("").Key("SNOWFLAKE_OAUTH_REFRESH_TOKEN").String()
+	t.SessionID = generic.Section("").Key("OKTA_SESSION_ID").String()
+
+	logging.Redact(t.AccessToken)
+	logging.Redact(t.RefreshToken)
+	logging.Redact(t.SessionID)
+
+	// Decrypt tokens that were encrypted at rest
+	v := vault.Open(c)
+	for _, value := range []*string{&t.AccessToken, &t.RefreshToken, &t.SessionID} {
+		if !vault.IsEncrypted(*value) {
+			continue
+		}
+
+		*value, err = v.Decrypt(*value)
+		if err != nil {
+			c.Logger.Debug("Couldn't decrypt token", "error", err)
+			return t, err
+		}
+		logging.Redact(*value)
+	}
+
+	return t, nil
+}
+
+func RemoveGenericCredentials(c config.Configuration) error {
+	var genericConfigPath = c.HomeDir + "/.gsc/" + c.ProfileName
+	var genericConfigFile = genericConfigPath + "/credentials"
+
+	err := os.Remove(genericConfigFile)
+	if os.IsNotExist(err) {
+		c.Logger.Debug("No generic configuration to remove", "error", err)
+		return nil
+	} else if err != nil {
+		fmt.Println(string(c.ColorFailure), "Generic: Couldn't remove credentials!")
+		c.Logger.Debug("Couldn't remove generic config", "error", err)
+		return err
+	}
+
+	// Only removes the profile directory if nothing else lives in it
+	os.Remove(genericConfigPath)
+
+	fmt.Println(string(c.ColorSuccess), "Generic: Profile", c.ProfileName, "removed from:", genericConfigFile)
+
+	return nil
+}
+
+// snowflakeHost is the hostname of the profile's Snowflake account
+func snowflakeHost(c config.Configuration) string {
+	return c.Profile.Account + ".snowflakecomputing.com"
+}
+
+// masked returns the credentials with the access token hidden, for URLs printed to the terminal
+func masked(t *config.Credentials) *config.Credentials {
+	m := *t
+	if m.AccessToken != "" {
+		m.AccessToken = "****"
+	}
+
+	return &m
+}
+
 func WriteODBCConfig(c config.Configuration, t *config.Credentials) error {
 	var odbcConfigFile = c.Profile.ODBCPath + "/odbc.ini"
 	var odbcInstConfigFile = c.Profile.ODBCPath + "/odbcinst.ini"
-	var serverURL = c.Profile.Account + ".snowflakecomputing.com"
+	var serverURL = snowflakeHost(c)
 
 	// Ensure ODBC path defined by user exists
-	if _, err := os.Stat(c.Profile.ODBCPath); os.IsNotExist(err) {
-		c.Logger.Debug("Couldn't find existing ODBC path, creating...", "error", err)
-		os.Mkdir(c.Profile.ODBCPath, os.ModePerm)
+	err := ensureDir(c, c.Profile.ODBCPath)
+	if err != nil {
+		c.Logger.Debug("Couldn't create ODBC path", "error", err)
+		return err
 	}
 
 	// Create profile DSN
@@ -78,7 +182,7 @@ func WriteODBCConfig(c config.Configuration, t *config.Credentials) error {
 		odbc.Section(c.ProfileName).Key("authenticator").SetValue("externalbrowser")
 	}
 
-	err = odbc.SaveTo(odbcConfigFile)
+	err = saveINI(c, odbc, odbcConfigFile)
 	if err != nil {
 		fmt.Println(string(c.ColorFailure), "ODBC: Couldn't write `odbc.ini`!")
 		c.Logger.Debug("Couldn't write `odbc.ini`", "error", err)
@@ -107,17 +211,58 @@ func WriteODBCConfig(c config.Configuration, t *config.Credentials) error {
 	return nil
 }
 
+func ReadODBCToken(c config.Configuration) string {
+	var odbcConfigFile = c.Profile.ODBCPath + "/odbc.ini"
+
+	odbc, err := ini.Load(odbcConfigFile)
+	if err != nil {
+		c.Logger.Debug("Couldn't read existing `odbc.ini`", "error", err)
+		return ""
+	}
+
+	return odbc.Section(c.ProfileName).Key("token").String()
+}
+
+func RemoveODBCToken(c config.Configuration) error {
+	var odbcConfigFile = c.Profile.ODBCPath + "/odbc.ini"
+
+	odbc, err := ini.Load(odbcConfigFile)
+	if err != nil {
+		c.Logger.Debug("No `odbc.ini` to remove token from", "error", err)
+		return nil
+	}
+
+	if !odbc.Section(c.ProfileName).HasKey("token") {
+		return nil
+	}
+
+	odbc.Section(c.ProfileName).DeleteKey("token")
+
+	err = saveINI(c, odbc, odbcConfigFile)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "ODBC: Couldn't write `odbc.ini`!")
+		c.Logger.Debug("Couldn't write `odbc.ini`", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "ODBC: Token for profile", c.ProfileName, "removed from:", odbcConfigFile)
+
+	return nil
+}
+
 func WriteDBTConfig(c config.Configuration, t *config.Credentials) error {
-	var dbtConfigPath = c.HomeDir + "/.dbt"
+	var dbtConfigPath = c.Profile.OutputOption("dbt", "dir", c.HomeDir+"/.dbt")
 	var dbtConfigFile = dbtConfigPath + "/profiles.yml"
 
 	var dbt = viper.New()
 	dbt.SetConfigFile(dbtConfigFile)
+	dbt.SetConfigPermissions(fileMode)
 
 	// Ensure DBT configuration directory exists
-	if _, err := os.Stat(dbtConfigPath); os.IsNotExist(err) {
-		c.Logger.Debug("Couldn't find existing DBT configuration directory, creating...", "error", err)
-		os.Mkdir(dbtConfigPath, os.ModePerm)
+	err := ensureDir(c, dbtConfigPath)
+	if err != nil {
+		c.Logger.Debug("Couldn't create DBT configuration directory", "error", err)
+		return err
 	}
 
 	if c.DefaultProfile != "" {
@@ -163,12 +308,15 @@ func WriteDBTConfig(c config.Configuration, t *config.Credentials) error {
 		dbt.Set(output, profile)
 	}
 
-	err := dbt.ReadInConfig()
+	err = dbt.ReadInConfig()
 	if err != nil {
 		fmt.Println(string(c.ColorSuccess), "DBT: No existing configuration found, creating file...")
 		c.Logger.Debug("Couldn't read existing DBT config", "error", err)
 	}
-	err = dbt.WriteConfig()
+	err = checkFile(c, dbtConfigFile)
+	if err == nil {
+		err = dbt.WriteConfig()
+	}
 	if err != nil {
 		fmt.Println(string(c.ColorFailure), "DBT: Couldn't write config!")
 		c.Logger.Debug("Couldn't write DBT config", "error", err)
@@ -179,3 +327,54 @@ func WriteDBTConfig(c config.Configuration, t *config.Credentials) error {
 
 	return nil
 }
+
+func RemoveDBTConfig(c config.Configuration) error {
+	var dbtConfigFile = c.Profile.OutputOption("dbt", "dir", c.HomeDir+"/.dbt") + "/profiles.yml"
+
+	var dbt = viper.New()
+	dbt.SetConfigFile(dbtConfigFile)
+
+	err := dbt.ReadInConfig()
+	if err != nil {
+		c.Logger.Debug("No DBT configuration to remove", "error", err)
+		return nil
+	}
+
+	settings := dbt.AllSettings()
+
+	// Viper has no notion of unsetting a key, so the output is removed from the raw settings,
+	// which are keyed in lower case
+	profile, ok := settings[strings.ToLower(c.Profile.DbtProfile)].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	outputs, ok := profile["outputs"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if _, ok := outputs[strings.ToLower(c.ProfileName)]; !ok {
+		return nil
+	}
+	delete(outputs, strings.ToLower(c.ProfileName))
+
+	var cleaned = viper.New()
+	cleaned.SetConfigFile(dbtConfigFile)
+	cleaned.SetConfigPermissions(fileMode)
+	for key, value := range settings {
+		cleaned.Set(key, value)
+	}
+
+	err = checkFile(c, dbtConfigFile)
+	if err == nil {
+		err = cleaned.WriteConfig()
+	}
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "DBT: Couldn't write config!")
+		c.Logger.Debug("Couldn't write DBT config", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "DBT: Profile", c.ProfileName, "removed from:", dbtConfigFile)
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package generator
+
+import (
+	"testing"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/hashicorp/go-hclog"
+)
+
+// testConfig returns a configuration with a home directory of its own
+func testConfig(t *testing.T) config.Configuration {
+	t.Helper()
+
+	return config.Configuration{
+		ProfileName: "dev",
+		HomeDir:     t.TempDir(),
+		Logger:      hclog.NewNullLogger(),
+	}
+}
@@ -0,0 +1,218 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/utils"
+)
+
+// jdbcWriter writes a per-profile JDBC `.properties` file and prints the matching
+// connection URL, for JVM-based tools
+type jdbcWriter struct{}
+
+func (w *jdbcWriter) Name() string {
+	return "jdbc"
+}
+
+func (w *jdbcWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *jdbcWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "path", c.HomeDir+"/.gsc/"+c.ProfileName+"/jdbc.properties")
+}
+
+func (w *jdbcWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var jdbcConfigFile = w.path(c)
+
+	properties := [][2]string{{"url", "jdbc:snowflake://" + snowflakeHost(c) + "/"}}
+	properties = append(properties, jdbcParameters(c, t)...)
+
+	err := writeProperties(c, jdbcConfigFile, properties)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "JDBC: Couldn't write properties!")
+		c.Logger.Debug("Couldn't write JDBC properties", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "JDBC: Profile", c.ProfileName, "written to:", jdbcConfigFile)
+	fmt.Println(string(c.ColorSuccess), "JDBC: URL:", jdbcURL(c, masked(t)))
+
+	return nil
+}
+
+func (w *jdbcWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return removeFile(c, "JDBC", w.path(c))
+}
+
+// jdbcParameters are the Snowflake JDBC driver connection parameters for the profile,
+// built from the same fields as the ODBC DSN
+func jdbcParameters(c config.Configuration, t *config.Credentials) [][2]string {
+	parameters := [][2]string{
+		{"user", c.Profile.Username},
+		{"role", c.Profile.Role},
+		{"warehouse", c.Profile.Warehouse},
+		{"db", c.Profile.Database},
+		{"schema", c.Profile.Schema},
+	}
+
+	if c.Profile.OAuth {
+		parameters = append(parameters, [2]string{"authenticator", "oauth"}, [2]string{"token", t.AccessToken})
+	} else {
+		parameters = append(parameters, [2]string{"authenticator", "externalbrowser"})
+	}
+
+	return parameters
+}
+
+// jdbcURL is a complete JDBC connection URL for the profile
+func jdbcURL(c config.Configuration, t *config.Credentials) string {
+	query := []string{}
+	for _, parameter := range jdbcParameters(c, t) {
+		query = append(query, parameter[0]+"="+url.QueryEscape(parameter[1]))
+	}
+
+	return "jdbc:snowflake://" + snowflakeHost(c) + "/?" + strings.Join(query, "&")
+}
+
+// writeProperties writes a Java `.properties` file that only the current user can read
+func writeProperties(c config.Configuration, path string, properties [][2]string) error {
+	err := ensureDir(c, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by gimme-snowflake-creds for profile " + c.ProfileName + "\n")
+	for _, property := range properties {
+		b.WriteString(property[0] + "=" + escapeProperty(property[1]) + "\n")
+	}
+
+	return ioutil.WriteFile(path, []byte(b.String()), fileMode)
+}
+
+// escapeProperty escapes a property value; keys are always plain identifiers
+func escapeProperty(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// propertyKey returns the key of a `.properties` line, or "" for comments and blank lines
+func propertyKey(line string) string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+		return ""
+	}
+
+	if i := strings.IndexAny(line, "=: \t"); i >= 0 {
+		return line[:i]
+	}
+
+	return line
+}
+
+// readPropertyLines reads a `.properties` file line by line, returning nothing when it doesn't exist
+func readPropertyLines(path string) ([]string, error) {
+	existing, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	} else if err != nil {
+		return nil, err
+	} else if len(strings.TrimSpace(string(existing))) == 0 {
+		return []string{}, nil
+	}
+
+	return strings.Split(strings.TrimRight(string(existing), "\n"), "\n"), nil
+}
+
+// upsertProperties sets properties in a `.properties` file that only the current user can
+// read, keeping the project's other settings as they are
+func upsertProperties(c config.Configuration, path string, properties [][2]string) error {
+	err := ensureDir(c, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	lines, err := readPropertyLines(path)
+	if err != nil {
+		return err
+	}
+
+	for _, property := range properties {
+		line := property[0] + "=" + escapeProperty(property[1])
+
+		replaced := false
+		for i, existing := range lines {
+			if propertyKey(existing) == property[0] {
+				lines[i] = line
+				replaced = true
+			}
+		}
+		if !replaced {
+			lines = append(lines, line)
+		}
+	}
+
+	return ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), fileMode)
+}
+
+// removeProperties deletes properties from a `.properties` file, reporting whether any were found
+func removeProperties(c config.Configuration, path string, keys []string) (bool, error) {
+	lines, err := readPropertyLines(path)
+	if err != nil {
+		return false, err
+	}
+
+	removed := false
+	kept := []string{}
+	for _, line := range lines {
+		if key := propertyKey(line); key != "" && utils.Contains(keys, key) {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	if !removed {
+		return false, nil
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return false, err
+	}
+
+	return true, ioutil.WriteFile(path, []byte(strings.Join(kept, "\n")+"\n"), fileMode)
+}
+
+// removeFile deletes a generated per-profile file
+func removeFile(c config.Configuration, label string, path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		fmt.Println(string(c.ColorFailure), label+": Couldn't remove", path+"!")
+		c.Logger.Debug("Couldn't remove generated file", "path", path, "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), label+": Profile", c.ProfileName, "removed from:", path)
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/google/uuid"
+)
+
+const jetbrainsComponent = "dataSourceStorageLocal"
+
+// jetbrainsProject is a `dataSources.local.xml`; components and data sources that aren't
+// ours are kept verbatim
+type jetbrainsProject struct {
+	XMLName    xml.Name                 `xml:"project"`
+	Version    string                   `xml:"version,attr"`
+	Components []jetbrainsComponentNode `xml:"component"`
+}
+
+type jetbrainsComponentNode struct {
+	Name        string                `xml:"name,attr"`
+	Attrs       []xml.Attr            `xml:",any,attr"`
+	Children    []jetbrainsNode       `xml:",any"`
+	DataSources []jetbrainsDataSource `xml:"data-source"`
+}
+
+// jetbrainsNode is any other element, kept verbatim
+type jetbrainsNode struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Inner   string     `xml:",innerxml"`
+}
+
+type jetbrainsDataSource struct {
+	Name  string     `xml:"name,attr"`
+	UUID  string     `xml:"uuid,attr"`
+	Attrs []xml.Attr `xml:",any,attr"`
+	Inner string     `xml:",innerxml"`
+}
+
+// jetbrainsWriter upserts a Snowflake data source, keyed by profile, into a JetBrains
+// project's `.idea/dataSources.local.xml`, for DataGrip and the other IDEs
+type jetbrainsWriter struct{}
+
+func (w *jetbrainsWriter) Name() string {
+	return "jetbrains"
+}
+
+func (w *jetbrainsWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+// path is inside the project directory, which has no sensible default
+func (w *jetbrainsWriter) path(c config.Configuration) string {
+	dir := c.Profile.OutputOption(w.Name(), "dir", "")
+	if dir == "" {
+		return ""
+	}
+
+	return dir + "/.idea/dataSources.local.xml"
+}
+
+// uuid is derived from the profile name so that re-running updates the same data source
+func (w *jetbrainsWriter) uuid(c config.Configuration) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte("gimme-snowflake-creds/"+c.ProfileName)).String()
+}
+
+func (w *jetbrainsWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var dataSourcesFile = w.path(c)
+
+	if dataSourcesFile == "" {
+		fmt.Println(string(c.ColorFailure), "JetBrains: Set `dir` in the jetbrains output options to a project directory!")
+		return errors.New("jetbrains output has no project directory")
+	}
+
+	project, err := loadJetBrainsProject(dataSourcesFile)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "JetBrains: Couldn't read `dataSources.local.xml`!")
+		c.Logger.Debug("Couldn't read JetBrains data sources", "error", err)
+		return err
+	}
+
+	var b bytes.Buffer
+	b.WriteString("\n      <driver-ref>snowflake</driver-ref>")
+	b.WriteString("\n      <jdbc-driver>net.snowflake.client.jdbc.SnowflakeDriver</jdbc-driver>")
+	b.WriteString("\n      <jdbc-url>")
+	xml.EscapeText(&b, []byte(jdbcURL(c, t)))
+	b.WriteString("</jdbc-url>\n      <user-name>")
+	xml.EscapeText(&b, []byte(c.Profile.Username))
+	b.WriteString("</user-name>\n    ")
+
+	dataSource := jetbrainsDataSource{Name: c.ProfileName, UUID: w.uuid(c), Inner: b.String()}
+
+	component := project.component()
+	replaced := false
+	for i, existing := range component.DataSources {
+		if existing.UUID == dataSource.UUID {
+			component.DataSources[i] = dataSource
+			replaced = true
+		}
+	}
+	if !replaced {
+		component.DataSources = append(component.DataSources, dataSource)
+	}
+
+	err = saveJetBrainsProject(c, dataSourcesFile, project)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "JetBrains: Couldn't write `dataSources.local.xml`!")
+		c.Logger.Debug("Couldn't write JetBrains data sources", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "JetBrains: Profile", c.ProfileName, "written to:", dataSourcesFile)
+
+	return nil
+}
+
+// Remove drops the profile's data source, as its URL holds the token
+func (w *jetbrainsWriter) Remove(ctx context.Context, c config.Configuration) error {
+	var dataSourcesFile = w.path(c)
+
+	if dataSourcesFile == "" {
+		return nil
+	}
+
+	project, err := loadJetBrainsProject(dataSourcesFile)
+	if err != nil {
+		c.Logger.Debug("Couldn't read JetBrains data sources", "error", err)
+		return nil
+	}
+
+	component := project.component()
+	kept := []jetbrainsDataSource{}
+	for _, existing := range component.DataSources {
+		if existing.UUID != w.uuid(c) {
+			kept = append(kept, existing)
+		}
+	}
+	if len(kept) == len(component.DataSources) {
+		return nil
+	}
+	component.DataSources = kept
+
+	err = saveJetBrainsProject(c, dataSourcesFile, project)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "JetBrains: Couldn't write `dataSources.local.xml`!")
+		c.Logger.Debug("Couldn't write JetBrains data sources", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "JetBrains: Profile", c.ProfileName, "removed from:", dataSourcesFile)
+
+	return nil
+}
+
+// component returns the local data source storage, adding it if it's missing
+func (p *jetbrainsProject) component() *jetbrainsComponentNode {
+	for i := range p.Components {
+		if p.Components[i].Name == jetbrainsComponent {
+			return &p.Components[i]
+		}
+	}
+
+	p.Components = append(p.Components, jetbrainsComponentNode{Name: jetbrainsComponent})
+
+	return &p.Components[len(p.Components)-1]
+}
+
+func loadJetBrainsProject(path string) (*jetbrainsProject, error) {
+	project := &jetbrainsProject{Version: "4"}
+
+	in, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return project, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return project, xml.Unmarshal(in, project)
+}
+
+func saveJetBrainsProject(c config.Configuration, path string, project *jetbrainsProject) error {
+	out, err := xml.MarshalIndent(project, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeSecretFile(c, path, xml.Header+string(out))
+}
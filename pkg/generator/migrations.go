@@ -0,0 +1,148 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// propertiesWriter upserts a JDBC connection into a schema-migration tool's `.properties`
+// configuration in a project directory, leaving the project's other settings alone. With
+// OAuth the URL holds the token, so the file has to be ignored by git
+type propertiesWriter struct {
+	name  string
+	label string
+	file  string
+	// keys for the JDBC URL and user
+	urlKey  string
+	userKey string
+}
+
+func (w *propertiesWriter) Name() string {
+	return w.name
+}
+
+func (w *propertiesWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *propertiesWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "dir", c.HomeDir+"/.gsc/"+c.ProfileName) + "/" + w.file
+}
+
+func (w *propertiesWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var configFile = w.path(c)
+
+	// The URL holds the token, which mustn't end up in the project's history
+	if c.Profile.OAuth {
+		err := ensureDir(c, filepath.Dir(configFile))
+		if err != nil {
+			return err
+		}
+
+		err = checkIgnored(c, configFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := upsertProperties(c, configFile, [][2]string{
+		{w.urlKey, jdbcURL(c, t)},
+		{w.userKey, c.Profile.Username},
+	})
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), w.label+": Couldn't write `"+w.file+"`!")
+		c.Logger.Debug("Couldn't write "+w.label+" configuration", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), w.label+": Profile", c.ProfileName, "written to:", configFile)
+
+	return nil
+}
+
+func (w *propertiesWriter) Remove(ctx context.Context, c config.Configuration) error {
+	var configFile = w.path(c)
+
+	// The URL holds the token
+	removed, err := removeProperties(c, configFile, []string{w.urlKey})
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), w.label+": Couldn't write `"+w.file+"`!")
+		c.Logger.Debug("Couldn't write "+w.label+" configuration", "error", err)
+		return err
+	}
+
+	if removed {
+		fmt.Println(string(c.ColorSuccess), w.label+": Profile", c.ProfileName, "removed from:", configFile)
+	}
+
+	return nil
+}
+
+// schemachangeWriter upserts the connection settings into a project's `schemachange-config.yml`.
+// The token itself goes into a file under `~/.gsc`, so the project never holds it
+type schemachangeWriter struct{}
+
+func (w *schemachangeWriter) Name() string {
+	return "schemachange"
+}
+
+func (w *schemachangeWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *schemachangeWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "dir", c.HomeDir+"/.gsc/"+c.ProfileName) + "/schemachange-config.yml"
+}
+
+func (w *schemachangeWriter) tokenPath(c config.Configuration) string {
+	return c.HomeDir + "/.gsc/" + c.ProfileName + "/snowflake-token"
+}
+
+func (w *schemachangeWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var configFile = w.path(c)
+
+	settings := [][2]string{
+		{"snowflake-account", c.Profile.Account},
+		{"snowflake-user", c.Profile.Username},
+		{"snowflake-role", c.Profile.Role},
+		{"snowflake-warehouse", c.Profile.Warehouse},
+		{"snowflake-database", c.Profile.Database},
+		{"snowflake-schema", c.Profile.Schema},
+	}
+
+	if c.Profile.OAuth {
+		settings = append(settings, [2]string{"snowflake-authenticator", "oauth"}, [2]string{"snowflake-token-path", w.tokenPath(c)})
+
+		err := writeSecretFile(c, w.tokenPath(c), t.AccessToken)
+		if err != nil {
+			fmt.Println(string(c.ColorFailure), "schemachange: Couldn't write token!")
+			c.Logger.Debug("Couldn't write schemachange token", "error", err)
+			return err
+		}
+	} else {
+		settings = append(settings, [2]string{"snowflake-authenticator", "externalbrowser"})
+	}
+
+	err := upsertYAML(c, configFile, settings)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "schemachange: Couldn't write `schemachange-config.yml`!")
+		c.Logger.Debug("Couldn't write schemachange configuration", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "schemachange: Profile", c.ProfileName, "written to:", configFile)
+
+	return nil
+}
+
+func (w *schemachangeWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return removeFile(c, "schemachange", w.tokenPath(c))
+}
+
+// Env provides the SNOWFLAKE_* variables schemachange reads, for `exec`
+func (w *schemachangeWriter) Env(c config.Configuration, t *config.Credentials) [][2]string {
+	return snowflakeEnv(c, t)
+}
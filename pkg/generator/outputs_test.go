@@ -0,0 +1,410 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// testProfile returns a profile connecting to every setting a writer can output
+func testProfile(oauth bool) config.Profile {
+	return config.Profile{
+		OAuth:     oauth,
+		Account:   "xy12345.us-east-1",
+		Username:  "gimme-user@example.com",
+		Role:      "ANALYST",
+		Warehouse: "COMPUTE_WH",
+		Database:  "ANALYTICS",
+		Schema:    "PUBLIC",
+	}
+}
+
+func TestWriterOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		writer  string
+		oauth   bool
+		options map[string]string
+		// git makes `project` a repository ignoring `ignored`
+		git     bool
+		ignored string
+		// files are relative to the home directory, and `$HOME` in them is replaced with it
+		files   map[string]string
+		want    map[string]string
+		wantErr bool
+		// removed is the files after Remove, where "" means the file doesn't exist
+		removed map[string]string
+	}{
+		{
+			name:   "SnowSQL",
+			writer: "snowsql",
+			oauth:  true,
+			files: map[string]string{
+				".snowsql/config": "[connections]\n# shared\nwarehousename = shared\n\n[connections.other]\nusername = someone\n",
+			},
+			want: map[string]string{
+				".snowsql/config": "[connections]\n# shared\nwarehousename = shared\n\n[connections.other]\nusername = someone\n\n[connections.dev]\naccountname   = xy12345.us-east-1\nusername      = gimme-user@example.com\nrolename      = ANALYST\ndbname        = ANALYTICS\nschemaname    = PUBLIC\nwarehousename = COMPUTE_WH\nauthenticator = oauth\ntoken         = access-token\n\n",
+			},
+			removed: map[string]string{
+				".snowsql/config": "[connections]\n# shared\nwarehousename = shared\n\n[connections.other]\nusername = someone\n\n[connections.dev]\naccountname   = xy12345.us-east-1\nusername      = gimme-user@example.com\nrolename      = ANALYST\ndbname        = ANALYTICS\nschemaname    = PUBLIC\nwarehousename = COMPUTE_WH\nauthenticator = oauth\n\n",
+			},
+		},
+		{
+			name:   "SnowSQL without OAuth",
+			writer: "snowsql",
+			files: map[string]string{
+				".snowsql/config": "[connections.dev]\nauthenticator = oauth\ntoken = stale\n",
+			},
+			want: map[string]string{
+				".snowsql/config": "[connections.dev]\nauthenticator = externalbrowser\naccountname   = xy12345.us-east-1\nusername      = gimme-user@example.com\nrolename      = ANALYST\ndbname        = ANALYTICS\nschemaname    = PUBLIC\nwarehousename = COMPUTE_WH\n\n",
+			},
+			removed: map[string]string{
+				".snowsql/config": "[connections.dev]\nauthenticator = externalbrowser\naccountname   = xy12345.us-east-1\nusername      = gimme-user@example.com\nrolename      = ANALYST\ndbname        = ANALYTICS\nschemaname    = PUBLIC\nwarehousename = COMPUTE_WH\n\n",
+			},
+		},
+		{
+			name:    "connections.toml",
+			writer:  "connections",
+			oauth:   true,
+			options: map[string]string{"default": "true"},
+			files: map[string]string{
+				".snowflake/connections.toml": "# shared\n[other]\naccount = \"other\" # keep\n",
+				".snowflake/config.toml":      "[cli.logs]\nlevel = \"info\"\n",
+			},
+			want: map[string]string{
+				".snowflake/connections.toml": "# shared\n[other]\naccount = \"other\" # keep\n\n[dev]\naccount = \"xy12345.us-east-1\"\nauthenticator = \"oauth\"\ndatabase = \"ANALYTICS\"\nrole = \"ANALYST\"\nschema = \"PUBLIC\"\ntoken = \"access-token\"\nuser = \"gimme-user@example.com\"\nwarehouse = \"COMPUTE_WH\"\n",
+				".snowflake/config.toml":      "default_connection_name = \"dev\"\n[cli.logs]\nlevel = \"info\"\n",
+			},
+			removed: map[string]string{
+				".snowflake/connections.toml": "# shared\n[other]\naccount = \"other\" # keep\n\n[dev]\naccount = \"xy12345.us-east-1\"\nauthenticator = \"oauth\"\ndatabase = \"ANALYTICS\"\nrole = \"ANALYST\"\nschema = \"PUBLIC\"\nuser = \"gimme-user@example.com\"\nwarehouse = \"COMPUTE_WH\"\n",
+			},
+		},
+		{
+			name:   "connections.toml without OAuth",
+			writer: "connections",
+			files: map[string]string{
+				".snowflake/connections.toml": "[dev]\nauthenticator = \"oauth\"\ntoken = \"stale\"\n",
+			},
+			want: map[string]string{
+				".snowflake/connections.toml": "[dev]\nauthenticator = \"externalbrowser\"\naccount = \"xy12345.us-east-1\"\ndatabase = \"ANALYTICS\"\nrole = \"ANALYST\"\nschema = \"PUBLIC\"\nuser = \"gimme-user@example.com\"\nwarehouse = \"COMPUTE_WH\"\n",
+			},
+			removed: map[string]string{
+				".snowflake/connections.toml": "[dev]\nauthenticator = \"externalbrowser\"\naccount = \"xy12345.us-east-1\"\ndatabase = \"ANALYTICS\"\nrole = \"ANALYST\"\nschema = \"PUBLIC\"\nuser = \"gimme-user@example.com\"\nwarehouse = \"COMPUTE_WH\"\n",
+			},
+		},
+		{
+			name:   "JDBC",
+			writer: "jdbc",
+			oauth:  true,
+			want: map[string]string{
+				".gsc/dev/jdbc.properties": "# Generated by gimme-snowflake-creds for profile dev\nurl=jdbc:snowflake://xy12345.us-east-1.snowflakecomputing.com/\nuser=gimme-user@example.com\nrole=ANALYST\nwarehouse=COMPUTE_WH\ndb=ANALYTICS\nschema=PUBLIC\nauthenticator=oauth\ntoken=access-token\n",
+			},
+			removed: map[string]string{
+				".gsc/dev/jdbc.properties": "",
+			},
+		},
+		{
+			name:   "SQLAlchemy",
+			writer: "sqlalchemy",
+			oauth:  true,
+			files: map[string]string{
+				".gsc/dev/.env": "# app\nOTHER=1\n",
+			},
+			want: map[string]string{
+				".gsc/dev/.env": "# app\nOTHER=1\nSNOWFLAKE_SQLALCHEMY_URL='snowflake://gimme-user%40example.com@xy12345.us-east-1/ANALYTICS/PUBLIC?authenticator=oauth&role=ANALYST&token=access-token&warehouse=COMPUTE_WH'\n",
+			},
+			removed: map[string]string{
+				".gsc/dev/.env": "# app\nOTHER=1\n",
+			},
+		},
+		{
+			name:    "SQLAlchemy without .env",
+			writer:  "sqlalchemy",
+			oauth:   true,
+			options: map[string]string{"env": "false"},
+			want: map[string]string{
+				".gsc/dev/.env": "",
+			},
+		},
+		{
+			name:   "Terraform",
+			writer: "terraform",
+			oauth:  true,
+			want: map[string]string{
+				".gsc/dev/terraform.env": "# Generated by gimme-snowflake-creds for profile dev\nexport SNOWFLAKE_ACCOUNT='xy12345.us-east-1'\nexport SNOWFLAKE_USER='gimme-user@example.com'\nexport SNOWFLAKE_ROLE='ANALYST'\nexport SNOWFLAKE_WAREHOUSE='COMPUTE_WH'\nexport SNOWFLAKE_AUTHENTICATOR='OAUTH'\nexport SNOWFLAKE_TOKEN='access-token'\n",
+			},
+			removed: map[string]string{
+				".gsc/dev/terraform.env": "",
+			},
+		},
+		{
+			name:   "Terraform without OAuth",
+			writer: "terraform",
+			want: map[string]string{
+				".gsc/dev/terraform.env": "# Generated by gimme-snowflake-creds for profile dev\nexport SNOWFLAKE_ACCOUNT='xy12345.us-east-1'\nexport SNOWFLAKE_USER='gimme-user@example.com'\nexport SNOWFLAKE_ROLE='ANALYST'\nexport SNOWFLAKE_WAREHOUSE='COMPUTE_WH'\nexport SNOWFLAKE_AUTHENTICATOR='EXTERNALBROWSER'\n",
+			},
+		},
+		{
+			name:   "Airflow",
+			writer: "airflow",
+			oauth:  true,
+			files: map[string]string{
+				".gsc/dev/airflow-connections.json": "{\"other\": {\"conn_type\": \"postgres\"}}\n",
+			},
+			want: map[string]string{
+				".gsc/dev/airflow-connections.json": "{\n  \"other\": {\n    \"conn_type\": \"postgres\"\n  },\n  \"snowflake_dev\": {\n    \"conn_type\": \"snowflake\",\n    \"login\": \"gimme-user@example.com\",\n    \"schema\": \"PUBLIC\",\n    \"extra\": {\n      \"account\": \"xy12345.us-east-1\",\n      \"authenticator\": \"oauth\",\n      \"database\": \"ANALYTICS\",\n      \"role\": \"ANALYST\",\n      \"token\": \"access-token\",\n      \"warehouse\": \"COMPUTE_WH\"\n    }\n  }\n}\n",
+				".gsc/dev/.env":                     "AIRFLOW_CONN_SNOWFLAKE_DEV='{\"conn_type\":\"snowflake\",\"login\":\"gimme-user@example.com\",\"schema\":\"PUBLIC\",\"extra\":{\"account\":\"xy12345.us-east-1\",\"authenticator\":\"oauth\",\"database\":\"ANALYTICS\",\"role\":\"ANALYST\",\"token\":\"access-token\",\"warehouse\":\"COMPUTE_WH\"}}'\n",
+			},
+			removed: map[string]string{
+				".gsc/dev/airflow-connections.json": "{\n  \"other\": {\n    \"conn_type\": \"postgres\"\n  }\n}\n",
+				".gsc/dev/.env":                     "",
+			},
+		},
+		{
+			name:    "Airflow YAML and URI",
+			writer:  "airflow",
+			oauth:   true,
+			options: map[string]string{"path": "$HOME/connections.yaml", "format": "uri", "id": "warehouse"},
+			want: map[string]string{
+				"connections.yaml": "warehouse:\n  conn_type: snowflake\n  login: gimme-user@example.com\n  schema: PUBLIC\n  extra:\n    account: xy12345.us-east-1\n    authenticator: oauth\n    database: ANALYTICS\n    role: ANALYST\n    token: access-token\n    warehouse: COMPUTE_WH\n",
+				".gsc/dev/.env":    "AIRFLOW_CONN_WAREHOUSE='snowflake://gimme-user%40example.com@/PUBLIC?account=xy12345.us-east-1&authenticator=oauth&database=ANALYTICS&role=ANALYST&token=access-token&warehouse=COMPUTE_WH'\n",
+			},
+			removed: map[string]string{
+				"connections.yaml": "",
+				".gsc/dev/.env":    "",
+			},
+		},
+		{
+			name:    "Streamlit",
+			writer:  "streamlit",
+			oauth:   true,
+			options: map[string]string{"dir": "$HOME/app", "connection": "warehouse"},
+			files: map[string]string{
+				"app/.streamlit/secrets.toml": "# app secrets\napi_key = \"abc\"\n",
+			},
+			want: map[string]string{
+				"app/.streamlit/secrets.toml": "# app secrets\napi_key = \"abc\"\n\n[connections.warehouse]\naccount = \"xy12345.us-east-1\"\nauthenticator = \"oauth\"\ndatabase = \"ANALYTICS\"\nrole = \"ANALYST\"\nschema = \"PUBLIC\"\ntoken = \"access-token\"\nuser = \"gimme-user@example.com\"\nwarehouse = \"COMPUTE_WH\"\n",
+			},
+			removed: map[string]string{
+				"app/.streamlit/secrets.toml": "# app secrets\napi_key = \"abc\"\n\n[connections.warehouse]\naccount = \"xy12345.us-east-1\"\nauthenticator = \"oauth\"\ndatabase = \"ANALYTICS\"\nrole = \"ANALYST\"\nschema = \"PUBLIC\"\nuser = \"gimme-user@example.com\"\nwarehouse = \"COMPUTE_WH\"\n",
+			},
+		},
+		{
+			name:    "DBeaver",
+			writer:  "dbeaver",
+			oauth:   true,
+			options: map[string]string{"path": "$HOME/dbeaver/data-sources.json"},
+			files: map[string]string{
+				"dbeaver/data-sources.json": "{\"folders\": {}, \"connections\": {\"other\": {\"provider\": \"postgresql\"}, \"gsc-dev\": {\"folder\": \"Snowflake\", \"configuration\": {\"provider-properties\": {\"@dbeaver-default-editor@\": \"sql\"}}}}}\n",
+			},
+			want: map[string]string{
+				"dbeaver/data-sources.json": "{\n\t\"connections\": {\n\t\t\"gsc-dev\": {\n\t\t\t\"configuration\": {\n\t\t\t\t\"auth-model\": \"native\",\n\t\t\t\t\"database\": \"ANALYTICS\",\n\t\t\t\t\"host\": \"xy12345.us-east-1.snowflakecomputing.com\",\n\t\t\t\t\"properties\": {\n\t\t\t\t\t\"authenticator\": \"oauth\",\n\t\t\t\t\t\"token\": \"access-token\"\n\t\t\t\t},\n\t\t\t\t\"provider-properties\": {\n\t\t\t\t\t\"@dbeaver-default-editor@\": \"sql\",\n\t\t\t\t\t\"@dbeaver-role@\": \"ANALYST\",\n\t\t\t\t\t\"@dbeaver-schema@\": \"PUBLIC\",\n\t\t\t\t\t\"@dbeaver-warehouse@\": \"COMPUTE_WH\"\n\t\t\t\t},\n\t\t\t\t\"type\": \"dev\",\n\t\t\t\t\"user\": \"gimme-user@example.com\"\n\t\t\t},\n\t\t\t\"driver\": \"snowflake\",\n\t\t\t\"folder\": \"Snowflake\",\n\t\t\t\"name\": \"dev\",\n\t\t\t\"provider\": \"snowflake\"\n\t\t},\n\t\t\"other\": {\n\t\t\t\"provider\": \"postgresql\"\n\t\t}\n\t},\n\t\"folders\": {}\n}\n",
+			},
+			removed: map[string]string{
+				"dbeaver/data-sources.json": "{\n\t\"connections\": {\n\t\t\"gsc-dev\": {\n\t\t\t\"configuration\": {\n\t\t\t\t\"auth-model\": \"native\",\n\t\t\t\t\"database\": \"ANALYTICS\",\n\t\t\t\t\"host\": \"xy12345.us-east-1.snowflakecomputing.com\",\n\t\t\t\t\"properties\": {\n\t\t\t\t\t\"authenticator\": \"oauth\"\n\t\t\t\t},\n\t\t\t\t\"provider-properties\": {\n\t\t\t\t\t\"@dbeaver-default-editor@\": \"sql\",\n\t\t\t\t\t\"@dbeaver-role@\": \"ANALYST\",\n\t\t\t\t\t\"@dbeaver-schema@\": \"PUBLIC\",\n\t\t\t\t\t\"@dbeaver-warehouse@\": \"COMPUTE_WH\"\n\t\t\t\t},\n\t\t\t\t\"type\": \"dev\",\n\t\t\t\t\"user\": \"gimme-user@example.com\"\n\t\t\t},\n\t\t\t\"driver\": \"snowflake\",\n\t\t\t\"folder\": \"Snowflake\",\n\t\t\t\"name\": \"dev\",\n\t\t\t\"provider\": \"snowflake\"\n\t\t},\n\t\t\"other\": {\n\t\t\t\"provider\": \"postgresql\"\n\t\t}\n\t},\n\t\"folders\": {}\n}\n",
+			},
+		},
+		{
+			name:    "JetBrains",
+			writer:  "jetbrains",
+			oauth:   true,
+			options: map[string]string{"dir": "$HOME/project"},
+			files: map[string]string{
+				"project/.idea/dataSources.local.xml": "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<project version=\"4\">\n  <component name=\"dataSourceStorageLocal\" created-in=\"DB-231\">\n    <data-source name=\"other\" uuid=\"1234\">\n      <database-info product=\"PostgreSQL\" />\n    </data-source>\n  </component>\n</project>\n",
+			},
+			want: map[string]string{
+				"project/.idea/dataSources.local.xml": "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<project version=\"4\">\n  <component name=\"dataSourceStorageLocal\" created-in=\"DB-231\">\n    <data-source name=\"other\" uuid=\"1234\">\n      <database-info product=\"PostgreSQL\" />\n    </data-source>\n    <data-source name=\"dev\" uuid=\"38b31821-99a6-5826-bef9-583f119d48f1\">\n      <driver-ref>snowflake</driver-ref>\n      <jdbc-driver>net.snowflake.client.jdbc.SnowflakeDriver</jdbc-driver>\n      <jdbc-url>jdbc:snowflake://xy12345.us-east-1.snowflakecomputing.com/?user=gimme-user%40example.com&amp;role=ANALYST&amp;warehouse=COMPUTE_WH&amp;db=ANALYTICS&amp;schema=PUBLIC&amp;authenticator=oauth&amp;token=access-token</jdbc-url>\n      <user-name>gimme-user@example.com</user-name>\n    </data-source>\n  </component>\n</project>\n",
+			},
+			removed: map[string]string{
+				"project/.idea/dataSources.local.xml": "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<project version=\"4\">\n  <component name=\"dataSourceStorageLocal\" created-in=\"DB-231\">\n    <data-source name=\"other\" uuid=\"1234\">\n      <database-info product=\"PostgreSQL\" />\n    </data-source>\n  </component>\n</project>\n",
+			},
+		},
+		{
+			name:    "JetBrains without a project",
+			writer:  "jetbrains",
+			oauth:   true,
+			wantErr: true,
+		},
+		{
+			name:   "Tableau",
+			writer: "tableau",
+			oauth:  true,
+			want: map[string]string{
+				".gsc/dev/dev.tds": "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<datasource formatted-name=\"dev\" inline=\"true\" version=\"18.1\">\n  <connection class=\"federated\">\n    <named-connections>\n      <named-connection caption=\"xy12345.us-east-1.snowflakecomputing.com\" name=\"snowflake.dev\">\n        <connection authentication=\"oauth\" class=\"snowflake\" dbname=\"ANALYTICS\" schema=\"PUBLIC\" server=\"xy12345.us-east-1.snowflakecomputing.com\" service=\"ANALYST\" username=\"gimme-user@example.com\" warehouse=\"COMPUTE_WH\"></connection>\n      </named-connection>\n    </named-connections>\n  </connection>\n</datasource>\n",
+			},
+			removed: map[string]string{
+				".gsc/dev/dev.tds": "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<datasource formatted-name=\"dev\" inline=\"true\" version=\"18.1\">\n  <connection class=\"federated\">\n    <named-connections>\n      <named-connection caption=\"xy12345.us-east-1.snowflakecomputing.com\" name=\"snowflake.dev\">\n        <connection authentication=\"oauth\" class=\"snowflake\" dbname=\"ANALYTICS\" schema=\"PUBLIC\" server=\"xy12345.us-east-1.snowflakecomputing.com\" service=\"ANALYST\" username=\"gimme-user@example.com\" warehouse=\"COMPUTE_WH\"></connection>\n      </named-connection>\n    </named-connections>\n  </connection>\n</datasource>\n",
+			},
+		},
+		{
+			name:    "schemachange",
+			writer:  "schemachange",
+			oauth:   true,
+			options: map[string]string{"dir": "$HOME/project"},
+			files: map[string]string{
+				"project/schemachange-config.yml": "# schemachange\nroot-folder: migrations\nsnowflake-role: OLD\n",
+			},
+			want: map[string]string{
+				"project/schemachange-config.yml": "# schemachange\nroot-folder: migrations\nsnowflake-role: ANALYST\nsnowflake-account: xy12345.us-east-1\nsnowflake-user: gimme-user@example.com\nsnowflake-warehouse: COMPUTE_WH\nsnowflake-database: ANALYTICS\nsnowflake-schema: PUBLIC\nsnowflake-authenticator: oauth\nsnowflake-token-path: $HOME/.gsc/dev/snowflake-token\n",
+				".gsc/dev/snowflake-token":        "access-token\n",
+			},
+			removed: map[string]string{
+				"project/schemachange-config.yml": "# schemachange\nroot-folder: migrations\nsnowflake-role: ANALYST\nsnowflake-account: xy12345.us-east-1\nsnowflake-user: gimme-user@example.com\nsnowflake-warehouse: COMPUTE_WH\nsnowflake-database: ANALYTICS\nsnowflake-schema: PUBLIC\nsnowflake-authenticator: oauth\nsnowflake-token-path: $HOME/.gsc/dev/snowflake-token\n",
+				".gsc/dev/snowflake-token":        "",
+			},
+		},
+		{
+			name:    "Kubernetes",
+			writer:  "kubernetes",
+			oauth:   true,
+			options: map[string]string{"name": "snowflake", "namespace": "data", "key-token": "token"},
+			want: map[string]string{
+				".gsc/dev/secret.yaml": "# Generated by gimme-snowflake-creds for profile dev\napiVersion: v1\nkind: Secret\nmetadata:\n  name: snowflake\n  namespace: data\ntype: Opaque\nstringData:\n  SNOWFLAKE_ACCOUNT: xy12345.us-east-1\n  SNOWFLAKE_USER: gimme-user@example.com\n  SNOWFLAKE_ROLE: ANALYST\n  SNOWFLAKE_WAREHOUSE: COMPUTE_WH\n  SNOWFLAKE_DATABASE: ANALYTICS\n  SNOWFLAKE_SCHEMA: PUBLIC\n  SNOWFLAKE_AUTHENTICATOR: oauth\n  token: access-token\n",
+			},
+			removed: map[string]string{
+				".gsc/dev/secret.yaml": "",
+			},
+		},
+		{
+			name:   "Docker",
+			writer: "docker",
+			oauth:  true,
+			want: map[string]string{
+				".gsc/dev/docker.env": "# Generated by gimme-snowflake-creds for profile dev\nSNOWFLAKE_ACCOUNT=xy12345.us-east-1\nSNOWFLAKE_USER=gimme-user@example.com\nSNOWFLAKE_ROLE=ANALYST\nSNOWFLAKE_WAREHOUSE=COMPUTE_WH\nSNOWFLAKE_DATABASE=ANALYTICS\nSNOWFLAKE_SCHEMA=PUBLIC\nSNOWFLAKE_AUTHENTICATOR=oauth\nSNOWFLAKE_TOKEN=access-token\n",
+			},
+			removed: map[string]string{
+				".gsc/dev/docker.env": "",
+			},
+		},
+		{
+			name:    "Flyway",
+			writer:  "flyway",
+			oauth:   true,
+			options: map[string]string{"dir": "$HOME/project"},
+			git:     true,
+			ignored: "flyway.conf\n",
+			files: map[string]string{
+				"project/flyway.conf": "# Flyway\nflyway.locations=filesystem:sql\n",
+			},
+			want: map[string]string{
+				"project/flyway.conf": "# Flyway\nflyway.locations=filesystem:sql\nflyway.url=jdbc:snowflake://xy12345.us-east-1.snowflakecomputing.com/?user=gimme-user%40example.com&role=ANALYST&warehouse=COMPUTE_WH&db=ANALYTICS&schema=PUBLIC&authenticator=oauth&token=access-token\nflyway.user=gimme-user@example.com\n",
+			},
+			removed: map[string]string{
+				"project/flyway.conf": "# Flyway\nflyway.locations=filesystem:sql\nflyway.user=gimme-user@example.com\n",
+			},
+		},
+		{
+			name:    "Flyway not ignored by git",
+			writer:  "flyway",
+			oauth:   true,
+			options: map[string]string{"dir": "$HOME/project"},
+			git:     true,
+			files: map[string]string{
+				"project/flyway.conf": "# Flyway\nflyway.locations=filesystem:sql\n",
+			},
+			want: map[string]string{
+				"project/flyway.conf": "# Flyway\nflyway.locations=filesystem:sql\n",
+			},
+			wantErr: true,
+		},
+		{
+			name:   "Liquibase",
+			writer: "liquibase",
+			oauth:  true,
+			want: map[string]string{
+				".gsc/dev/liquibase.properties": "url=jdbc:snowflake://xy12345.us-east-1.snowflakecomputing.com/?user=gimme-user%40example.com&role=ANALYST&warehouse=COMPUTE_WH&db=ANALYTICS&schema=PUBLIC&authenticator=oauth&token=access-token\nusername=gimme-user@example.com\n",
+			},
+			removed: map[string]string{
+				".gsc/dev/liquibase.properties": "username=gimme-user@example.com\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := testConfig(t)
+			c.Profile = testProfile(tt.oauth)
+			c.Profile.Outputs = []string{tt.writer}
+
+			expand := func(s string) string {
+				return strings.ReplaceAll(s, "$HOME", c.HomeDir)
+			}
+
+			if len(tt.options) > 0 {
+				options := map[string]string{}
+				for key, value := range tt.options {
+					options[key] = expand(value)
+				}
+				c.Profile.OutputOptions = map[string]map[string]string{tt.writer: options}
+			}
+
+			if tt.git {
+				project := filepath.Join(c.HomeDir, "project")
+				if err := os.MkdirAll(project, dirMode); err != nil {
+					t.Fatal(err)
+				}
+				if out, err := exec.Command("git", "init", "-q", project).CombinedOutput(); err != nil {
+					t.Skipf("git init failed: %v: %s", err, out)
+				}
+				writeTestFile(t, filepath.Join(project, ".gitignore"), tt.ignored)
+			}
+
+			for path, content := range tt.files {
+				path = filepath.Join(c.HomeDir, path)
+				if err := os.MkdirAll(filepath.Dir(path), dirMode); err != nil {
+					t.Fatal(err)
+				}
+				writeTestFile(t, path, expand(content))
+			}
+
+			w, ok := Lookup(tt.writer)
+			if !ok {
+				t.Fatalf("Lookup(%q) = false", tt.writer)
+			}
+			if !w.Enabled(c.Profile) {
+				t.Errorf("Enabled() = false with outputs %v", c.Profile.Outputs)
+			}
+
+			err := w.Write(context.Background(), c, &config.Credentials{AccessToken: "access-token"})
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Write() error = nil")
+				}
+			} else if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			checkFiles(t, c.HomeDir, tt.want, expand)
+
+			if err := w.Remove(context.Background(), c); err != nil {
+				t.Fatalf("Remove() error = %v", err)
+			}
+			checkFiles(t, c.HomeDir, tt.removed, expand)
+		})
+	}
+}
+
+// checkFiles compares files under home with what they should hold, where "" means the
+// file shouldn't exist
+func checkFiles(t *testing.T, home string, want map[string]string, expand func(string) string) {
+	t.Helper()
+
+	for path, content := range want {
+		path = filepath.Join(home, path)
+
+		if content == "" {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%v exists: %v", path, err)
+			}
+			continue
+		}
+
+		if got := readTestFile(t, path); got != expand(content) {
+			t.Errorf("%v:\n%s\nwant:\n%s", path, got, expand(content))
+		}
+	}
+}
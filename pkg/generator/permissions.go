@@ -0,0 +1,108 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"gopkg.in/ini.v1"
+)
+
+const (
+	dirMode  os.FileMode = 0700
+	fileMode os.FileMode = 0600
+)
+
+var (
+	errForeignOwner = errors.New("file is owned by another user")
+	errNotIgnored   = errors.New("file isn't ignored by git")
+)
+
+// ensureDir creates a missing directory so that only the current user can access it;
+// existing directories, such as a shared ODBC path, are left as they are
+func ensureDir(c config.Configuration, path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		c.Logger.Debug("Couldn't find existing directory, creating...", "path", path)
+		return os.MkdirAll(path, dirMode)
+	}
+
+	return nil
+}
+
+// checkFile refuses token-bearing files owned by another user, and restricts
+// existing ones that are readable by the group or the world
+func checkFile(c config.Configuration, path string) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !ownedByCurrentUser(info) {
+		fmt.Println(string(c.ColorFailure), "Refusing to write tokens to", path+": it's owned by another user!")
+		return errForeignOwner
+	}
+
+	if info.Mode().Perm()&0077 != 0 {
+		fmt.Println(string(c.ColorFailure), "Warning:", path, "was accessible by other users, restricting it to", fmt.Sprintf("%04o", fileMode))
+		return os.Chmod(path, fileMode)
+	}
+
+	return nil
+}
+
+// saveINI writes a token-bearing INI file that only the current user can read
+func saveINI(c config.Configuration, f *ini.File, path string) error {
+	err := checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fileMode)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteTo(out)
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// writeSecretFile writes a single secret to a file that only the current user can read
+func writeSecretFile(c config.Configuration, path string, secret string) error {
+	err := ensureDir(c, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, []byte(secret+"\n"), fileMode)
+}
+
+// checkIgnored refuses token-bearing files in a git repository that git doesn't ignore,
+// since project configuration is usually committed. Files outside a repository are fine
+func checkIgnored(c config.Configuration, path string) error {
+	err := exec.Command("git", "-C", filepath.Dir(path), "check-ignore", "-q", filepath.Base(path)).Run()
+
+	// git check-ignore exits with 1 when the file isn't ignored, and 128 outside a repository
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		fmt.Println(string(c.ColorFailure), "Refusing to write tokens to", path+": add it to .gitignore so it can't be committed!")
+		return errNotIgnored
+	}
+
+	return nil
+}
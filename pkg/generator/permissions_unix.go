@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package generator
+
+import (
+	"os"
+	"syscall"
+)
+
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true
+	}
+
+	return int(stat.Uid) == os.Getuid()
+}
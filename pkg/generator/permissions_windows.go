@@ -0,0 +1,11 @@
+//go:build windows
+// +build windows
+
+package generator
+
+import "os"
+
+// Windows files are protected by ACLs rather than owner and mode bits
+func ownedByCurrentUser(info os.FileInfo) bool {
+	return true
+}
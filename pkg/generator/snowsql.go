@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/utils"
+	"gopkg.in/ini.v1"
+)
+
+// snowsqlWriter writes a `[connections.<profile>]` section into the SnowSQL config,
+// preserving other connections and comments
+type snowsqlWriter struct{}
+
+func (w *snowsqlWriter) Name() string {
+	return "snowsql"
+}
+
+func (w *snowsqlWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *snowsqlWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "path", c.HomeDir+"/.snowsql/config")
+}
+
+func (w *snowsqlWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var snowsqlConfigFile = w.path(c)
+	var section = "connections." + c.ProfileName
+
+	err := ensureDir(c, filepath.Dir(snowsqlConfigFile))
+	if err != nil {
+		c.Logger.Debug("Couldn't create SnowSQL configuration directory", "error", err)
+		return err
+	}
+
+	snowsql, err := ini.Load(snowsqlConfigFile)
+	if err != nil {
+		fmt.Println(string(c.ColorSuccess), "SnowSQL: No existing configuration found, creating file...")
+		c.Logger.Debug("Couldn't read existing SnowSQL config", "error", err)
+		snowsql = ini.Empty()
+	}
+
+	// Keys are created in the connection's own section, as looking them up would fall back
+	// to the parent `[connections]` section
+	connection := snowsql.Section(section)
+	connection.NewKey("accountname", c.Profile.Account)
+	connection.NewKey("username", c.Profile.Username)
+	connection.NewKey("rolename", c.Profile.Role)
+	connection.NewKey("dbname", c.Profile.Database)
+	connection.NewKey("schemaname", c.Profile.Schema)
+	connection.NewKey("warehousename", c.Profile.Warehouse)
+
+	if c.Profile.OAuth {
+		connection.NewKey("authenticator", "oauth")
+		connection.NewKey("token", t.AccessToken)
+	} else {
+		connection.NewKey("authenticator", "externalbrowser")
+		connection.DeleteKey("token")
+	}
+
+	err = saveINI(c, snowsql, snowsqlConfigFile)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "SnowSQL: Couldn't write config!")
+		c.Logger.Debug("Couldn't write SnowSQL config", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "SnowSQL: Profile", c.ProfileName, "written to:", snowsqlConfigFile)
+
+	return nil
+}
+
+func (w *snowsqlWriter) Remove(ctx context.Context, c config.Configuration) error {
+	var snowsqlConfigFile = w.path(c)
+	var section = "connections." + c.ProfileName
+
+	snowsql, err := ini.Load(snowsqlConfigFile)
+	if err != nil {
+		c.Logger.Debug("No SnowSQL configuration to remove token from", "error", err)
+		return nil
+	}
+
+	if !utils.Contains(snowsql.Section(section).KeyStrings(), "token") {
+		return nil
+	}
+
+	snowsql.Section(section).DeleteKey("token")
+
+	err = saveINI(c, snowsql, snowsqlConfigFile)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "SnowSQL: Couldn't write config!")
+		c.Logger.Debug("Couldn't write SnowSQL config", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "SnowSQL: Token for profile", c.ProfileName, "removed from:", snowsqlConfigFile)
+
+	return nil
+}
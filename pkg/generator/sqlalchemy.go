@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+const sqlalchemyEnv = "SNOWFLAKE_SQLALCHEMY_URL"
+
+// sqlalchemyWriter prints a SQLAlchemy URL for snowflake-sqlalchemy and writes it
+// into a per-profile `.env`
+type sqlalchemyWriter struct{}
+
+func (w *sqlalchemyWriter) Name() string {
+	return "sqlalchemy"
+}
+
+func (w *sqlalchemyWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *sqlalchemyWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "path", c.HomeDir+"/.gsc/"+c.ProfileName+"/.env")
+}
+
+func (w *sqlalchemyWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	fmt.Println(string(c.ColorSuccess), "SQLAlchemy: URL:", sqlalchemyURL(c, masked(t)))
+
+	if !c.Profile.OutputFlag(w.Name(), "env", true) {
+		return nil
+	}
+
+	var envFile = w.path(c)
+
+	err := upsertEnvFile(c, envFile, w.Env(c, t))
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "SQLAlchemy: Couldn't write `.env`!")
+		c.Logger.Debug("Couldn't write SQLAlchemy `.env`", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "SQLAlchemy: Profile", c.ProfileName, "written to:", envFile)
+
+	return nil
+}
+
+func (w *sqlalchemyWriter) Env(c config.Configuration, t *config.Credentials) [][2]string {
+	return [][2]string{{sqlalchemyEnv, sqlalchemyURL(c, t)}}
+}
+
+func (w *sqlalchemyWriter) Remove(ctx context.Context, c config.Configuration) error {
+	var envFile = w.path(c)
+
+	removed, err := removeEnvVars(c, envFile, []string{sqlalchemyEnv})
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "SQLAlchemy: Couldn't write `.env`!")
+		c.Logger.Debug("Couldn't write SQLAlchemy `.env`", "error", err)
+		return err
+	}
+
+	if removed {
+		fmt.Println(string(c.ColorSuccess), "SQLAlchemy: Profile", c.ProfileName, "removed from:", envFile)
+	}
+
+	return nil
+}
+
+// sqlalchemyURL renders a snowflake-sqlalchemy URL, escaping every component
+func sqlalchemyURL(c config.Configuration, t *config.Credentials) string {
+	query := url.Values{}
+	query.Set("warehouse", c.Profile.Warehouse)
+	query.Set("role", c.Profile.Role)
+
+	if c.Profile.OAuth {
+		query.Set("authenticator", "oauth")
+		query.Set("token", t.AccessToken)
+	} else {
+		query.Set("authenticator", "externalbrowser")
+	}
+
+	u := url.URL{
+		Scheme:   "snowflake",
+		User:     url.User(c.Profile.Username),
+		Host:     c.Profile.Account,
+		Path:     "/" + c.Profile.Database + "/" + c.Profile.Schema,
+		RawPath:  "/" + url.PathEscape(c.Profile.Database) + "/" + url.PathEscape(c.Profile.Schema),
+		RawQuery: query.Encode(),
+	}
+
+	return u.String()
+}
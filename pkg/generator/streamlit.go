@@ -0,0 +1,79 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// streamlitWriter upserts a `[connections.snowflake]` table into a Streamlit `secrets.toml`,
+// keeping the app's other secrets and comments
+type streamlitWriter struct{}
+
+func (w *streamlitWriter) Name() string {
+	return "streamlit"
+}
+
+func (w *streamlitWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+// dir is the project directory, or the home directory for Streamlit's global secrets
+func (w *streamlitWriter) dir(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "dir", c.HomeDir) + "/.streamlit"
+}
+
+func (w *streamlitWriter) table(c config.Configuration) []string {
+	return []string{"connections", c.Profile.OutputOption(w.Name(), "connection", "snowflake")}
+}
+
+func (w *streamlitWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var streamlitDir = w.dir(c)
+	var secretsFile = streamlitDir + "/secrets.toml"
+
+	err := ensureDir(c, streamlitDir)
+	if err != nil {
+		c.Logger.Debug("Couldn't create Streamlit directory", "error", err)
+		return err
+	}
+
+	if _, err := os.Stat(secretsFile); os.IsNotExist(err) {
+		fmt.Println(string(c.ColorSuccess), "Streamlit: No existing `secrets.toml`, creating file...")
+	}
+
+	// Only the connection's table is edited
+	deleted := []string{}
+	if !c.Profile.OAuth {
+		deleted = append(deleted, "token")
+	}
+
+	err = upsertTOML(c, secretsFile, w.table(c), snowflakeConnection(c, t), deleted...)
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Streamlit: Couldn't write `secrets.toml`!")
+		c.Logger.Debug("Couldn't write `secrets.toml`", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "Streamlit: Profile", c.ProfileName, "written to:", secretsFile)
+
+	return nil
+}
+
+func (w *streamlitWriter) Remove(ctx context.Context, c config.Configuration) error {
+	var secretsFile = w.dir(c) + "/secrets.toml"
+
+	removed, err := removeTOMLKey(c, secretsFile, w.table(c), "token")
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Streamlit: Couldn't write `secrets.toml`!")
+		c.Logger.Debug("Couldn't write `secrets.toml`", "error", err)
+		return err
+	}
+
+	if removed {
+		fmt.Println(string(c.ColorSuccess), "Streamlit: Token for profile", c.ProfileName, "removed from:", secretsFile)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// tableauDataSource is a Tableau `.tds` data source with a single Snowflake connection
+type tableauDataSource struct {
+	XMLName       xml.Name          `xml:"datasource"`
+	FormattedName string            `xml:"formatted-name,attr"`
+	Inline        string            `xml:"inline,attr"`
+	Version       string            `xml:"version,attr"`
+	Connection    tableauFederation `xml:"connection"`
+}
+
+type tableauFederation struct {
+	Class            string                   `xml:"class,attr"`
+	NamedConnections []tableauNamedConnection `xml:"named-connections>named-connection"`
+}
+
+type tableauNamedConnection struct {
+	Caption    string            `xml:"caption,attr"`
+	Name       string            `xml:"name,attr"`
+	Connection tableauConnection `xml:"connection"`
+}
+
+type tableauConnection struct {
+	Authentication string `xml:"authentication,attr"`
+	Class          string `xml:"class,attr"`
+	DBName         string `xml:"dbname,attr"`
+	Schema         string `xml:"schema,attr"`
+	Server         string `xml:"server,attr"`
+	Service        string `xml:"service,attr"`
+	Username       string `xml:"username,attr"`
+	Warehouse      string `xml:"warehouse,attr"`
+}
+
+// tableauWriter writes a per-profile Tableau `.tds` that signs in to Snowflake with OAuth
+type tableauWriter struct{}
+
+func (w *tableauWriter) Name() string {
+	return "tableau"
+}
+
+func (w *tableauWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *tableauWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "path", c.HomeDir+"/.gsc/"+c.ProfileName+"/"+c.ProfileName+".tds")
+}
+
+func (w *tableauWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var tdsFile = w.path(c)
+
+	dataSource := tableauDataSource{
+		FormattedName: c.ProfileName,
+		Inline:        "true",
+		Version:       "18.1",
+		Connection: tableauFederation{
+			Class: "federated",
+			NamedConnections: []tableauNamedConnection{{
+				Caption: snowflakeHost(c),
+				Name:    "snowflake." + c.ProfileName,
+				// The role is Tableau's "service"
+				Connection: tableauConnection{
+					Authentication: "oauth",
+					Class:          "snowflake",
+					DBName:         c.Profile.Database,
+					Schema:         c.Profile.Schema,
+					Server:         snowflakeHost(c),
+					Service:        c.Profile.Role,
+					Username:       c.Profile.Username,
+					Warehouse:      c.Profile.Warehouse,
+				},
+			}},
+		},
+	}
+
+	out, err := xml.MarshalIndent(dataSource, "", "  ")
+	if err == nil {
+		err = writeSecretFile(c, tdsFile, xml.Header+string(out))
+	}
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Tableau: Couldn't write `.tds`!")
+		c.Logger.Debug("Couldn't write Tableau data source", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "Tableau: Profile", c.ProfileName, "written to:", tdsFile)
+
+	return nil
+}
+
+func (w *tableauWriter) tokenless() {}
+
+// Remove leaves the data source in place, as Tableau signs in itself and it holds no token
+func (w *tableauWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return nil
+}
@@ -0,0 +1,61 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// terraformWriter writes a sourceable per-profile file with the environment variables
+// read by the Terraform Snowflake provider
+type terraformWriter struct{}
+
+func (w *terraformWriter) Name() string {
+	return "terraform"
+}
+
+func (w *terraformWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), false)
+}
+
+func (w *terraformWriter) path(c config.Configuration) string {
+	return c.Profile.OutputOption(w.Name(), "path", c.HomeDir+"/.gsc/"+c.ProfileName+"/terraform.env")
+}
+
+func (w *terraformWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	var terraformEnvFile = w.path(c)
+
+	err := writeShellEnv(c, terraformEnvFile, w.Env(c, t))
+	if err != nil {
+		fmt.Println(string(c.ColorFailure), "Terraform: Couldn't write environment!")
+		c.Logger.Debug("Couldn't write Terraform environment", "error", err)
+		return err
+	}
+
+	fmt.Println(string(c.ColorSuccess), "Terraform: Profile", c.ProfileName, "written to:", terraformEnvFile)
+
+	return nil
+}
+
+func (w *terraformWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return removeFile(c, "Terraform", w.path(c))
+}
+
+// Env is snowflakeEnv less the database and schema, which Terraform resources name
+// themselves, with the authenticator in the upper case the provider expects
+func (w *terraformWriter) Env(c config.Configuration, t *config.Credentials) [][2]string {
+	vars := [][2]string{}
+	for _, v := range snowflakeEnv(c, t) {
+		switch v[0] {
+		case "SNOWFLAKE_DATABASE", "SNOWFLAKE_SCHEMA":
+			continue
+		case "SNOWFLAKE_AUTHENTICATOR":
+			v[1] = strings.ToUpper(v[1])
+		}
+		vars = append(vars, v)
+	}
+
+	return vars
+}
@@ -0,0 +1,478 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/utils"
+	"github.com/pelletier/go-toml"
+)
+
+// TOML files written here belong to other tools and are edited by hand, so rather than
+// round-tripping them through the TOML library, which drops comments and reorders keys,
+// only the statements of the keys being set are edited. The result is parsed again and
+// compared with the original before it's written, so an edit can't silently damage the file
+
+var bareTOMLKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// errTOMLEdit is returned when an edited file wouldn't hold exactly the intended change
+var errTOMLEdit = errors.New("refusing to write TOML file: the edit would change more than the connection")
+
+// tomlStatement is a header, a `key = value` pair including every line of a multi-line
+// value, or a blank or comment line
+type tomlStatement struct {
+	lines []string
+	// header is the name of a `[table]` header
+	header []string
+	// array marks an `[[array]]` header, which ends a table without naming one
+	array bool
+	// key is set for a pair with a plain, undotted key
+	key string
+}
+
+func (s tomlStatement) isHeader() bool {
+	return s.header != nil || s.array
+}
+
+// upsertTOML sets keys in a table of a TOML file that only the current user can read, and
+// deletes others from it, leaving the rest of the file as it is. An empty table is the
+// root table
+func upsertTOML(c config.Configuration, path string, table []string, values map[string]interface{}, deleted ...string) error {
+	err := checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	in, tree, err := readTOML(path)
+	if err != nil {
+		return err
+	}
+
+	statements := parseTOMLStatements(in)
+	body, end, ok := tomlTable(statements, table)
+	if !ok {
+		if tree.HasPath(table) {
+			return fmt.Errorf("[%v] isn't a table of its own and can't be edited", strings.Join(table, "."))
+		}
+
+		if n := len(statements); n > 0 && strings.TrimSpace(statements[n-1].lines[0]) != "" {
+			statements = append(statements, tomlStatement{lines: []string{""}})
+		}
+		statements = append(statements, tomlStatement{lines: []string{"[" + formatTOMLKey(table) + "]"}, header: table})
+		body, end = len(statements), len(statements)
+	}
+
+	edited, _, err := editTOMLTable(statements[body:end], values, deleted)
+	if err != nil {
+		return err
+	}
+
+	out := joinTOMLStatements(statements[:body], edited, statements[end:])
+
+	err = checkTOMLEdit(tree, out, table, values, deleted)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, out, fileMode)
+}
+
+// removeTOMLKey deletes a key from a table of a TOML file, reporting whether it was found
+func removeTOMLKey(c config.Configuration, path string, table []string, key string) (bool, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return false, nil
+	}
+
+	in, tree, err := readTOML(path)
+	if err != nil {
+		return false, err
+	}
+
+	statements := parseTOMLStatements(in)
+	body, end, ok := tomlTable(statements, table)
+	if !ok {
+		return false, nil
+	}
+
+	edited, removed, err := editTOMLTable(statements[body:end], nil, []string{key})
+	if err != nil || !removed {
+		return false, err
+	}
+
+	out := joinTOMLStatements(statements[:body], edited, statements[end:])
+
+	err = checkTOMLEdit(tree, out, table, nil, []string{key})
+	if err != nil {
+		return false, err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return false, err
+	}
+
+	return true, ioutil.WriteFile(path, out, fileMode)
+}
+
+// readTOML reads a TOML file, refusing to edit one that isn't valid TOML
+func readTOML(path string) ([]byte, *toml.Tree, error) {
+	in, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		tree, _ := toml.TreeFromMap(map[string]interface{}{})
+		return nil, tree, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	tree, err := toml.LoadBytes(in)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return in, tree, nil
+}
+
+// checkTOMLEdit parses an edited file and compares it with the original tree: the table's
+// keys must hold their new values, deleted keys must be gone and nothing else may differ
+func checkTOMLEdit(before *toml.Tree, out []byte, table []string, values map[string]interface{}, deleted []string) error {
+	after, err := toml.LoadBytes(out)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errTOMLEdit, err)
+	}
+
+	for key, value := range values {
+		path := append(append([]string{}, table...), key)
+		if !reflect.DeepEqual(after.GetPath(path), value) {
+			return fmt.Errorf("%w: %v isn't set", errTOMLEdit, key)
+		}
+	}
+
+	// Apart from the keys set or deleted, both trees must be the same
+	existed := len(table) == 0 || before.HasPath(table)
+	want, got := before.ToMap(), after.ToMap()
+	for _, m := range []map[string]interface{}{want, got} {
+		t := m
+		for _, name := range table {
+			next, ok := t[name].(map[string]interface{})
+			if !ok {
+				t = nil
+				break
+			}
+			t = next
+		}
+		if t == nil {
+			continue
+		}
+
+		for key := range values {
+			delete(t, key)
+		}
+		for _, key := range deleted {
+			delete(t, key)
+		}
+	}
+	if !existed {
+		removeEmptyTOMLTable(got, table)
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		return errTOMLEdit
+	}
+
+	return nil
+}
+
+// removeEmptyTOMLTable drops a table added by an edit once its keys have been set aside
+func removeEmptyTOMLTable(m map[string]interface{}, table []string) {
+	if len(table) == 0 {
+		return
+	}
+
+	child, ok := m[table[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	removeEmptyTOMLTable(child, table[1:])
+	if len(child) == 0 {
+		delete(m, table[0])
+	}
+}
+
+// editTOMLTable sets and deletes keys in the statements of a table, replacing existing keys
+// in place and adding new ones after the table's last key. It reports whether anything was deleted
+func editTOMLTable(statements []tomlStatement, values map[string]interface{}, deleted []string) ([]tomlStatement, bool, error) {
+	edited := []tomlStatement{}
+	removed := false
+	set := map[string]bool{}
+	for _, statement := range statements {
+		key := statement.key
+		if key != "" && utils.Contains(deleted, key) {
+			removed = true
+			continue
+		}
+
+		if value, found := values[key]; key != "" && found {
+			if set[key] {
+				continue
+			}
+
+			line, err := formatTOMLLine(key, value)
+			if err != nil {
+				return nil, false, err
+			}
+			edited = append(edited, tomlStatement{lines: []string{line}, key: key})
+			set[key] = true
+			continue
+		}
+
+		edited = append(edited, statement)
+	}
+
+	keys := []string{}
+	for key := range values {
+		if !set[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	added := []tomlStatement{}
+	for _, key := range keys {
+		line, err := formatTOMLLine(key, values[key])
+		if err != nil {
+			return nil, false, err
+		}
+		added = append(added, tomlStatement{lines: []string{line}, key: key})
+	}
+
+	// Blank lines and comments at the end introduce whatever follows the table
+	last := len(edited)
+	for last > 0 && isTOMLTrivia(edited[last-1]) {
+		last--
+	}
+
+	return append(append(append([]tomlStatement{}, edited[:last]...), added...), edited[last:]...), removed, nil
+}
+
+func isTOMLTrivia(s tomlStatement) bool {
+	line := strings.TrimSpace(s.lines[0])
+	return len(s.lines) == 1 && (line == "" || strings.HasPrefix(line, "#"))
+}
+
+// tomlTable finds the statements holding a table's keys, from below its header, or the top
+// of the file for the root table, up to the next header
+func tomlTable(statements []tomlStatement, table []string) (int, int, bool) {
+	body := 0
+	if len(table) > 0 {
+		body = -1
+		for i, statement := range statements {
+			if statement.header != nil && formatTOMLKey(statement.header) == formatTOMLKey(table) {
+				body = i + 1
+				break
+			}
+		}
+		if body < 0 {
+			return 0, 0, false
+		}
+	}
+
+	end := len(statements)
+	for i := body; i < len(statements); i++ {
+		if statements[i].isHeader() {
+			end = i
+			break
+		}
+	}
+
+	return body, end, true
+}
+
+func joinTOMLStatements(groups ...[]tomlStatement) []byte {
+	lines := []string{}
+	for _, group := range groups {
+		for _, statement := range group {
+			lines = append(lines, statement.lines...)
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// parseTOMLStatements splits a TOML document into statements, following strings, arrays
+// and inline tables across lines so that a line inside a value is never taken for a key or
+// a header
+func parseTOMLStatements(in []byte) []tomlStatement {
+	if len(in) == 0 {
+		return nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(in), "\n"), "\n")
+	statements := []tomlStatement{}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		statement := tomlStatement{lines: []string{lines[i]}}
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "[["):
+			statement.array = true
+		case strings.HasPrefix(line, "["):
+			statement.header, _ = parseTOMLKey(line[1:])
+		default:
+			key, rest := parseTOMLKey(strings.TrimLeft(lines[i], " \t"))
+			if len(key) == 1 && strings.HasPrefix(rest, "=") {
+				statement.key = key[0]
+			}
+
+			// The value may continue on the following lines
+			last := tomlValueEnd(lines, i, len(lines[i])-len(rest))
+			statement.lines = lines[i : last+1]
+			i = last
+		}
+
+		statements = append(statements, statement)
+	}
+
+	return statements
+}
+
+// tomlValueEnd returns the line a value starting at a column of a line ends on
+func tomlValueEnd(lines []string, i int, col int) int {
+	const (
+		plain = iota
+		basic
+		literal
+		multilineBasic
+		multilineLiteral
+	)
+
+	state, depth := plain, 0
+	for ; i < len(lines); i, col = i+1, 0 {
+		line := lines[i]
+		for j := col; j < len(line); j++ {
+			ch := line[j]
+			switch state {
+			case plain:
+				switch {
+				case ch == '#':
+					j = len(line)
+				case strings.HasPrefix(line[j:], `"""`):
+					state, j = multilineBasic, j+2
+				case strings.HasPrefix(line[j:], `'''`):
+					state, j = multilineLiteral, j+2
+				case ch == '"':
+					state = basic
+				case ch == '\'':
+					state = literal
+				case ch == '[' || ch == '{':
+					depth++
+				case ch == ']' || ch == '}':
+					depth--
+				}
+			case basic, multilineBasic:
+				if ch == '\\' {
+					j++
+				} else if state == basic && ch == '"' {
+					state = plain
+				} else if state == multilineBasic && strings.HasPrefix(line[j:], `"""`) {
+					// Up to two more quotes belong to the string
+					j += 2
+					for n := 0; n < 2 && j+1 < len(line) && line[j+1] == '"'; n++ {
+						j++
+					}
+					state = plain
+				}
+			case literal:
+				if ch == '\'' {
+					state = plain
+				}
+			case multilineLiteral:
+				if strings.HasPrefix(line[j:], `'''`) {
+					j += 2
+					for n := 0; n < 2 && j+1 < len(line) && line[j+1] == '\''; n++ {
+						j++
+					}
+					state = plain
+				}
+			}
+		}
+
+		// Single-line strings end with their line, even when unterminated
+		if state == basic || state == literal {
+			state = plain
+		}
+		if state == plain && depth <= 0 {
+			return i
+		}
+	}
+
+	return len(lines) - 1
+}
+
+// parseTOMLKey parses a possibly dotted and quoted key up to an unquoted `]` or `=`,
+// returning its parts and the rest of the line
+func parseTOMLKey(s string) ([]string, string) {
+	parts := []string{}
+	var part strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote == '"' && ch == '\\' && i+1 < len(s):
+			i++
+			part.WriteByte(s[i])
+		case quote != 0 && ch == quote:
+			quote = 0
+		case quote != 0:
+			part.WriteByte(ch)
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '.':
+			parts = append(parts, strings.TrimSpace(part.String()))
+			part.Reset()
+		case ch == ']' || ch == '=':
+			return append(parts, strings.TrimSpace(part.String())), s[i:]
+		default:
+			part.WriteByte(ch)
+		}
+	}
+
+	return append(parts, strings.TrimSpace(part.String())), ""
+}
+
+// formatTOMLKey formats a dotted key, quoting parts that can't be bare
+func formatTOMLKey(key []string) string {
+	parts := []string{}
+	for _, part := range key {
+		if !bareTOMLKey.MatchString(part) {
+			part = strconv.Quote(part)
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// formatTOMLLine formats a `key = value` line, leaving the value's encoding to the TOML library
+func formatTOMLLine(key string, value interface{}) (string, error) {
+	tree, err := toml.TreeFromMap(map[string]interface{}{"value": value})
+	if err != nil {
+		return "", err
+	}
+
+	encoded, err := tree.ToTomlString()
+	if err != nil {
+		return "", err
+	}
+
+	return formatTOMLKey([]string{key}) + " = " + strings.TrimPrefix(strings.TrimSpace(encoded), "value = "), nil
+}
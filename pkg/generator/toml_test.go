@@ -0,0 +1,195 @@
+package generator
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pelletier/go-toml"
+)
+
+func TestUpsertTOML(t *testing.T) {
+	connection := map[string]interface{}{"user": "me", "token": "new"}
+
+	tests := []struct {
+		name    string
+		in      string
+		table   []string
+		values  map[string]interface{}
+		deleted []string
+		want    string
+		err     bool
+	}{
+		{
+			name:   "new file",
+			table:  []string{"connections", "snowflake"},
+			values: connection,
+			want:   "[connections.snowflake]\ntoken = \"new\"\nuser = \"me\"\n",
+		},
+		{
+			name:   "comments and order kept",
+			in:     "# app secrets\napi_key = \"abc\" # keep\n\n[conn]\n# mine\nuser = \"old\"\naccount = \"acme\"\n\n# other\n[other]\nz = 1\na = 2\n",
+			table:  []string{"conn"},
+			values: connection,
+			want:   "# app secrets\napi_key = \"abc\" # keep\n\n[conn]\n# mine\nuser = \"me\"\naccount = \"acme\"\ntoken = \"new\"\n\n# other\n[other]\nz = 1\na = 2\n",
+		},
+		{
+			name:   "multi-line array",
+			in:     "[conn]\nhosts = [\n  [\"a\"],\n]\n",
+			table:  []string{"conn"},
+			values: connection,
+			want:   "[conn]\nhosts = [\n  [\"a\"],\n]\ntoken = \"new\"\nuser = \"me\"\n",
+		},
+		{
+			name:   "multi-line basic string holding a header and a key",
+			in:     "[conn]\nprivate_key = \"\"\"\n-----BEGIN-----\n[not a header]\nuser = \"fake\"\n-----END-----\"\"\"\nuser = \"old\"\n",
+			table:  []string{"conn"},
+			values: connection,
+			want:   "[conn]\nprivate_key = \"\"\"\n-----BEGIN-----\n[not a header]\nuser = \"fake\"\n-----END-----\"\"\"\nuser = \"me\"\ntoken = \"new\"\n",
+		},
+		{
+			name:   "multi-line literal string replaced",
+			in:     "[conn]\ntoken = '''\nold\ntoken'''\nuser = \"old\"\n",
+			table:  []string{"conn"},
+			values: connection,
+			want:   "[conn]\ntoken = \"new\"\nuser = \"me\"\n",
+		},
+		{
+			name:    "multi-line literal string deleted",
+			in:      "[conn]\ntoken = '''\nold\ntoken'''\nuser = \"old\"\n",
+			table:   []string{"conn"},
+			values:  map[string]interface{}{"user": "me"},
+			deleted: []string{"token"},
+			want:    "[conn]\nuser = \"me\"\n",
+		},
+		{
+			name:   "inline table across lines and dotted keys",
+			in:     "[conn]\nsession.timeout = 5\nparams = { a = 1, b = [\n  2,\n] }\n",
+			table:  []string{"conn"},
+			values: connection,
+			want:   "[conn]\nsession.timeout = 5\nparams = { a = 1, b = [\n  2,\n] }\ntoken = \"new\"\nuser = \"me\"\n",
+		},
+		{
+			name:   "root table",
+			in:     "# cli\n[cli.logs]\nsave_logs = true\n",
+			values: map[string]interface{}{"default_connection_name": "dev"},
+			want:   "default_connection_name = \"dev\"\n# cli\n[cli.logs]\nsave_logs = true\n",
+		},
+		{
+			name:   "quoted table name",
+			in:     "[\"my.dev\"]\nuser = \"old\"\n",
+			table:  []string{"my.dev"},
+			values: connection,
+			want:   "[\"my.dev\"]\nuser = \"me\"\ntoken = \"new\"\n",
+		},
+		{
+			name:   "table defined by dotted keys",
+			in:     "[connections]\nsnowflake.user = \"old\"\n",
+			table:  []string{"connections", "snowflake"},
+			values: connection,
+			err:    true,
+		},
+		{
+			name:   "invalid file",
+			in:     "[conn\n",
+			table:  []string{"conn"},
+			values: connection,
+			err:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "secrets.toml")
+			if tt.in != "" {
+				writeTestFile(t, path, tt.in)
+			}
+
+			err := upsertTOML(testConfig(t), path, tt.table, tt.values, tt.deleted...)
+			if tt.err {
+				if err == nil {
+					t.Fatal("upsertTOML() succeeded, want an error")
+				}
+				if tt.in != "" && readTestFile(t, path) != tt.in {
+					t.Error("file changed despite the error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := readTestFile(t, path)
+			if got != tt.want {
+				t.Errorf("got:\n%v\nwant:\n%v", got, tt.want)
+			}
+			if _, err := toml.LoadBytes([]byte(got)); err != nil {
+				t.Errorf("result doesn't parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestRemoveTOMLKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "connections.toml")
+	writeTestFile(t, path, "[dev]\nuser = \"me\"\ntoken = \"\"\"\nold\n\"\"\"\n\n[prod]\ntoken = \"keep\"\n")
+
+	removed, err := removeTOMLKey(testConfig(t), path, []string{"dev"}, "token")
+	if err != nil || !removed {
+		t.Fatalf("removeTOMLKey() = %v, %v, want true", removed, err)
+	}
+
+	want := "[dev]\nuser = \"me\"\n\n[prod]\ntoken = \"keep\"\n"
+	if got := readTestFile(t, path); got != want {
+		t.Errorf("got:\n%v\nwant:\n%v", got, want)
+	}
+
+	removed, err = removeTOMLKey(testConfig(t), path, []string{"dev"}, "token")
+	if err != nil || removed {
+		t.Errorf("removeTOMLKey() = %v, %v, want false", removed, err)
+	}
+}
+
+func TestCheckTOMLEdit(t *testing.T) {
+	before, err := toml.LoadBytes([]byte("[conn]\nuser = \"old\"\nprivate_key = \"secret\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]interface{}{"user": "me"}
+
+	err = checkTOMLEdit(before, []byte("[conn]\nuser = \"me\"\nprivate_key = \"secret\"\n"), []string{"conn"}, values, nil)
+	if err != nil {
+		t.Errorf("checkTOMLEdit() = %v for an edit of only user", err)
+	}
+
+	err = checkTOMLEdit(before, []byte("[conn]\nuser = \"me\"\nprivate_key = \"sec\nuser = \"me\"\"\n"), []string{"conn"}, values, nil)
+	if !errors.Is(err, errTOMLEdit) {
+		t.Errorf("checkTOMLEdit() = %v for invalid TOML, want %v", err, errTOMLEdit)
+	}
+
+	err = checkTOMLEdit(before, []byte("[conn]\nuser = \"me\"\nprivate_key = \"changed\"\n"), []string{"conn"}, values, nil)
+	if !errors.Is(err, errTOMLEdit) {
+		t.Errorf("checkTOMLEdit() = %v for a changed private_key, want %v", err, errTOMLEdit)
+	}
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+
+	err := ioutil.WriteFile(path, []byte(content), fileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// Writer generates one kind of output, such as a client configuration file, from a
+// profile and the credentials acquired for it
+type Writer interface {
+	// Name identifies the output in configuration and messages
+	Name() string
+	// Enabled reports whether the output is generated for the profile
+	Enabled(p config.Profile) bool
+	Write(ctx context.Context, c config.Configuration, t *config.Credentials) error
+	// Remove deletes the profile's tokens from the output
+	Remove(ctx context.Context, c config.Configuration) error
+}
+
+// EnvWriter is a Writer whose output is a set of environment variables, which `exec`
+// can also pass straight to a command
+type EnvWriter interface {
+	Writer
+	Env(c config.Configuration, t *config.Credentials) [][2]string
+}
+
+// tokenless is implemented by writers whose output never holds a token
+type tokenless interface {
+	tokenless()
+}
+
+var registry []Writer
+
+func init() {
+	Register(&genericWriter{})
+	Register(&odbcWriter{})
+	Register(&dbtWriter{})
+	Register(&snowsqlWriter{})
+	Register(&connectionsWriter{})
+	Register(&jdbcWriter{})
+	Register(&sqlalchemyWriter{})
+	Register(&terraformWriter{})
+	Register(&airflowWriter{})
+	Register(&streamlitWriter{})
+	Register(&dbeaverWriter{})
+	Register(&jetbrainsWriter{})
+	Register(&tableauWriter{})
+	Register(&schemachangeWriter{})
+	Register(&kubernetesWriter{})
+	Register(&dockerWriter{})
+	Register(&propertiesWriter{name: "flyway", label: "Flyway", file: "flyway.conf", urlKey: "flyway.url", userKey: "flyway.user"})
+	Register(&propertiesWriter{name: "liquibase", label: "Liquibase", file: "liquibase.properties", urlKey: "url", userKey: "username"})
+}
+
+// Register adds a writer to the registry; writers run in the order they're registered
+func Register(w Writer) {
+	if _, ok := Lookup(w.Name()); ok {
+		panic(fmt.Sprintf("generator: writer %v registered twice", w.Name()))
+	}
+
+	registry = append(registry, w)
+}
+
+// Writers returns every registered writer
+func Writers() []Writer {
+	return registry
+}
+
+// EnvWriters returns every registered writer that provides environment variables
+func EnvWriters() []EnvWriter {
+	writers := []EnvWriter{}
+	for _, w := range registry {
+		if e, ok := w.(EnvWriter); ok {
+			writers = append(writers, e)
+		}
+	}
+
+	return writers
+}
+
+// PlaintextOutputs lists the outputs enabled for a profile that write its token in
+// plaintext, which is every one but the generic output when `encrypt-token` is set
+func PlaintextOutputs(p config.Profile) []string {
+	outputs := []string{}
+	for _, w := range registry {
+		if _, ok := w.(tokenless); ok || w.Name() == "generic" || !w.Enabled(p) {
+			continue
+		}
+
+		outputs = append(outputs, w.Name())
+	}
+
+	return outputs
+}
+
+// Lookup returns the registered writer with the given name
+func Lookup(name string) (Writer, bool) {
+	for _, w := range registry {
+		if w.Name() == name {
+			return w, true
+		}
+	}
+
+	return nil, false
+}
+
+type genericWriter struct{}
+
+func (w *genericWriter) Name() string {
+	return "generic"
+}
+
+func (w *genericWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), p.Generic)
+}
+
+func (w *genericWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	return WriteGenericCredentials(c, t)
+}
+
+func (w *genericWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return RemoveGenericCredentials(c)
+}
+
+type odbcWriter struct{}
+
+func (w *odbcWriter) Name() string {
+	return "odbc"
+}
+
+func (w *odbcWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), true)
+}
+
+func (w *odbcWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	return WriteODBCConfig(c, t)
+}
+
+func (w *odbcWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return RemoveODBCToken(c)
+}
+
+type dbtWriter struct{}
+
+func (w *dbtWriter) Name() string {
+	return "dbt"
+}
+
+func (w *dbtWriter) Enabled(p config.Profile) bool {
+	return p.OutputEnabled(w.Name(), true)
+}
+
+func (w *dbtWriter) Write(ctx context.Context, c config.Configuration, t *config.Credentials) error {
+	return WriteDBTConfig(c, t)
+}
+
+func (w *dbtWriter) Remove(ctx context.Context, c config.Configuration) error {
+	return RemoveDBTConfig(c)
+}
@@ -0,0 +1,86 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+var writerNames = []string{
+	"generic", "odbc", "dbt", "snowsql", "connections", "jdbc", "sqlalchemy", "terraform", "airflow",
+	"streamlit", "dbeaver", "jetbrains", "tableau", "schemachange", "kubernetes", "docker", "flyway", "liquibase",
+}
+
+func TestRegistry(t *testing.T) {
+	names := []string{}
+	for _, w := range Writers() {
+		names = append(names, w.Name())
+	}
+	if !reflect.DeepEqual(names, writerNames) {
+		t.Errorf("Writers() = %v, want %v", names, writerNames)
+	}
+
+	for _, name := range writerNames {
+		if w, ok := Lookup(name); !ok || w.Name() != name {
+			t.Errorf("Lookup(%q) = %v, %v", name, w, ok)
+		}
+	}
+	if _, ok := Lookup("excel"); ok {
+		t.Errorf("Lookup() of an unknown writer = true")
+	}
+}
+
+func TestEnvWriters(t *testing.T) {
+	names := []string{}
+	for _, w := range EnvWriters() {
+		names = append(names, w.Name())
+	}
+
+	want := []string{"sqlalchemy", "terraform", "airflow", "schemachange"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("EnvWriters() = %v, want %v", names, want)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() of a duplicate didn't panic")
+		}
+	}()
+
+	Register(&jdbcWriter{})
+}
+
+func TestPlaintextOutputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs []string
+		want    []string
+	}{
+		{
+			name: "defaults",
+			want: []string{"odbc", "dbt"},
+		},
+		{
+			name:    "generic and Tableau hold no plaintext token",
+			outputs: []string{"generic", "tableau", "jdbc", "docker"},
+			want:    []string{"jdbc", "docker"},
+		},
+		{
+			name:    "registry order",
+			outputs: []string{"liquibase", "snowsql"},
+			want:    []string{"snowsql", "liquibase"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlaintextOutputs(config.Profile{Outputs: tt.outputs})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlaintextOutputs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
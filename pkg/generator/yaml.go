@@ -0,0 +1,96 @@
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"gopkg.in/yaml.v2"
+)
+
+// upsertYAML sets top-level scalar keys in a YAML file that only the current user can read.
+// Only the lines of those keys are rewritten, so other keys, their order and comments are kept
+func upsertYAML(c config.Configuration, path string, settings [][2]string) error {
+	err := ensureDir(c, filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+
+	err = checkFile(c, path)
+	if err != nil {
+		return err
+	}
+
+	in, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Refuse to edit anything but a mapping
+	var document interface{}
+	err = yaml.Unmarshal(in, &document)
+	if err != nil {
+		return err
+	}
+	if _, ok := document.(map[interface{}]interface{}); !ok && document != nil {
+		return fmt.Errorf("%v isn't a YAML mapping", path)
+	}
+
+	lines := []string{}
+	if len(in) > 0 {
+		lines = strings.Split(strings.TrimSuffix(string(in), "\n"), "\n")
+	}
+
+	for _, setting := range settings {
+		out, err := yaml.Marshal(yaml.MapSlice{{Key: setting[0], Value: setting[1]}})
+		if err != nil {
+			return err
+		}
+		line := strings.TrimSuffix(string(out), "\n")
+
+		replaced := false
+		kept := []string{}
+		for i := 0; i < len(lines); i++ {
+			if yamlKey(lines[i]) != setting[0] {
+				kept = append(kept, lines[i])
+				continue
+			}
+
+			// Drop a value that continues on indented lines
+			for i+1 < len(lines) && isIndented(lines[i+1]) {
+				i++
+			}
+			if !replaced {
+				kept = append(kept, line)
+				replaced = true
+			}
+		}
+		if !replaced {
+			kept = append(kept, line)
+		}
+		lines = kept
+	}
+
+	return ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), fileMode)
+}
+
+// yamlKey returns the top-level key set on a line, or "" for anything else
+func yamlKey(line string) string {
+	if line == "" || isIndented(line) || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+		return ""
+	}
+
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return ""
+	}
+
+	return strings.Trim(line[:i], `"'`)
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
@@ -0,0 +1,68 @@
+// Package gosnowflake connects Go programs using the Snowflake Go driver with the
+// profiles and tokens managed by gimme-snowflake-creds.
+//
+// It builds DSNs in the driver's format without depending on the driver itself; pass
+// them to sql.Open("snowflake", dsn), or to the driver's ParseDSN for a *Config.
+package gosnowflake
+
+import (
+	"net/url"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+// Profile holds the Snowflake connection settings of a gimme-snowflake-creds profile
+type Profile struct {
+	Username  string
+	Account   string
+	Database  string
+	Schema    string
+	Warehouse string
+	Role      string
+	// OAuth profiles connect with a token, others authenticate in the browser
+	OAuth     bool
+	KeepAlive bool
+}
+
+func newProfile(p config.Profile) Profile {
+	return Profile{
+		Username:  p.Username,
+		Account:   p.Account,
+		Database:  p.Database,
+		Schema:    p.Schema,
+		Warehouse: p.Warehouse,
+		Role:      p.Role,
+		OAuth:     p.OAuth,
+		KeepAlive: p.KeepAlive,
+	}
+}
+
+// Params are the driver connection parameters for a profile; the token is only used with OAuth
+func Params(p Profile, token string) url.Values {
+	params := url.Values{}
+	params.Set("warehouse", p.Warehouse)
+	params.Set("role", p.Role)
+
+	if p.OAuth {
+		params.Set("authenticator", "oauth")
+		params.Set("token", token)
+	} else {
+		params.Set("authenticator", "externalbrowser")
+	}
+
+	if p.KeepAlive {
+		params.Set("client_session_keep_alive", "true")
+	}
+
+	return params
+}
+
+// DSN builds a driver DSN, `user@account/database/schema?params`, for a profile
+func DSN(p Profile, token string) string {
+	dsn := p.Username + "@" + p.Account + "/" + url.PathEscape(p.Database)
+	if p.Schema != "" {
+		dsn += "/" + url.PathEscape(p.Schema)
+	}
+
+	return dsn + "?" + Params(p, token).Encode()
+}
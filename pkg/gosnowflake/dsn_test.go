@@ -0,0 +1,60 @@
+package gosnowflake
+
+import "testing"
+
+func TestDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		want    string
+	}{
+		{
+			name: "oauth",
+			profile: Profile{
+				OAuth:     true,
+				Username:  "user@example.com",
+				Account:   "acme",
+				Database:  "ANALYTICS",
+				Schema:    "PUBLIC",
+				Warehouse: "WH",
+				Role:      "ANALYST",
+			},
+			want: "user@example.com@acme/ANALYTICS/PUBLIC?authenticator=oauth&role=ANALYST&token=tok%2Fen&warehouse=WH",
+		},
+		{
+			name: "externalbrowser",
+			profile: Profile{
+				Username:  "user@example.com",
+				Account:   "acme",
+				Database:  "MY DB",
+				Warehouse: "WH",
+				Role:      "ANALYST",
+				KeepAlive: true,
+			},
+			want: "user@example.com@acme/MY%20DB?authenticator=externalbrowser&client_session_keep_alive=true&role=ANALYST&warehouse=WH",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DSN(tt.profile, "tok/en")
+			if got != tt.want {
+				t.Errorf("DSN() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	p := Profile{Warehouse: "WH", Role: "ANALYST"}
+
+	// Browser authentication never carries a token
+	if params := Params(p, "token"); params.Get("token") != "" || params.Get("authenticator") != "externalbrowser" {
+		t.Errorf("Params() = %v, want externalbrowser without a token", params)
+	}
+
+	p.OAuth = true
+	if params := Params(p, "token"); params.Get("token") != "token" || params.Get("authenticator") != "oauth" {
+		t.Errorf("Params() = %v, want oauth with the token", params)
+	}
+}
@@ -0,0 +1,156 @@
+package gosnowflake
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	okta "github.com/HGInsights/gimme-snowflake-creds/pkg/auth"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/generator"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+	"github.com/mitchellh/go-homedir"
+)
+
+// RefreshBefore is how long before expiry a token is refreshed
+const RefreshBefore = time.Minute
+
+var (
+	// ErrNoToken is returned when there's no usable token and it can't be refreshed
+	ErrNoToken = errors.New("no valid token: run gimme-snowflake-creds")
+	// ErrNoProfile is returned by Open when no profile is given and there's no default profile
+	ErrNoProfile = errors.New("no profile given and no default profile set")
+	// ErrNotOAuth is returned for profiles that authenticate in the browser rather than with a token
+	ErrNotOAuth = errors.New("profile doesn't use OAuth")
+)
+
+// TokenAccessor hands out a profile's access token from the token cache kept by
+// gimme-snowflake-creds, refreshing it when it's about to expire. It's safe for
+// concurrent use
+type TokenAccessor struct {
+	c  config.Configuration
+	mu sync.Mutex
+	t  *config.Credentials
+}
+
+// newTokenAccessor returns a TokenAccessor for a loaded configuration
+func newTokenAccessor(c config.Configuration) *TokenAccessor {
+	return &TokenAccessor{c: c}
+}
+
+// Open loads a profile from `~/.okta_snowflake_login_config`, or the default profile when
+// the name is empty, and returns a TokenAccessor for it
+func Open(profile string) (*TokenAccessor, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	// Profiles are loaded and validated just as the CLI does
+	v, logger, err := config.Read(home)
+	if err != nil {
+		return nil, err
+	}
+
+	// Profiles use OAuth unless they turn it off, as with the CLI's --oauth default
+	c := config.Configuration{Logger: logger, Profile: config.Profile{OAuth: true}}
+
+	if profile == "" {
+		profile = v.GetString("default")
+	}
+	if profile == "" {
+		return nil, ErrNoProfile
+	}
+
+	err = config.LoadProfile(v, &c, home, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	err = config.ValidateConfiguration(&c)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTokenAccessor(c), nil
+}
+
+// Profile returns the profile the accessor was opened for
+func (a *TokenAccessor) Profile() Profile {
+	return newProfile(a.c.Profile)
+}
+
+// Token returns a current access token
+func (a *TokenAccessor) Token() (string, error) {
+	if !a.c.Profile.OAuth {
+		return "", ErrNotOAuth
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.valid() {
+		return a.t.AccessToken, nil
+	}
+
+	// Another run of gimme-snowflake-creds may have renewed the cache
+	// Tokens encrypted with the vault can't be read without its key, which is only prompted for on a terminal
+	t, err := generator.ReadTokenCache(a.c)
+	if errors.Is(err, vault.ErrLocked) {
+		return "", err
+	}
+	if err == nil && t.AccessToken != "" {
+		a.t = t
+	}
+	if a.valid() {
+		return a.t.AccessToken, nil
+	}
+
+	refreshToken := ""
+	if a.t != nil {
+		refreshToken = a.t.RefreshToken
+	}
+	if refreshToken == "" {
+		return "", ErrNoToken
+	}
+
+	t, err = okta.Refresh(a.c, refreshToken)
+	if err != nil {
+		a.c.Logger.Debug("Unable to refresh token", "error", err)
+		return "", ErrNoToken
+	}
+
+	// Write the refreshed token back, so that other processes and the CLI pick it up
+	err = generator.WriteTokenCache(a.c, t)
+	if err != nil {
+		a.c.Logger.Debug("Unable to update token cache", "error", err)
+	}
+
+	a.t = t
+
+	return a.t.AccessToken, nil
+}
+
+// DSN returns a driver DSN carrying a current access token
+func (a *TokenAccessor) DSN() (string, error) {
+	token := ""
+
+	if a.c.Profile.OAuth {
+		var err error
+		token, err = a.Token()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return DSN(a.Profile(), token), nil
+}
+
+// valid reports whether the held token can still be used for a while
+func (a *TokenAccessor) valid() bool {
+	if a.t == nil || a.t.AccessToken == "" {
+		return false
+	}
+
+	return a.t.ExpiresAt.IsZero() || time.Until(a.t.ExpiresAt) > RefreshBefore
+}
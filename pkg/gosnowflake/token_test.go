@@ -0,0 +1,155 @@
+package gosnowflake
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/generator"
+	"github.com/hashicorp/go-hclog"
+)
+
+// testAccessor returns an accessor for an OAuth profile whose token endpoint is served by h
+func testAccessor(t *testing.T, h http.HandlerFunc) *TokenAccessor {
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	return newTokenAccessor(config.Configuration{
+		ProfileName: "test",
+		HomeDir:     t.TempDir(),
+		Logger:      hclog.NewNullLogger(),
+		Profile: config.Profile{
+			OAuth:     true,
+			ClientID:  "client",
+			IssuerURL: server.URL,
+			// A read-only store keeps the refresh token out of the OS keyring
+			SecretStore: "env",
+		},
+	})
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		name  string
+		token *config.Credentials
+		want  bool
+	}{
+		{"none", nil, false},
+		{"empty", &config.Credentials{}, false},
+		{"no expiry", &config.Credentials{AccessToken: "a"}, true},
+		{"fresh", &config.Credentials{AccessToken: "a", ExpiresAt: time.Now().Add(time.Hour)}, true},
+		{"about to expire", &config.Credentials{AccessToken: "a", ExpiresAt: time.Now().Add(RefreshBefore / 2)}, false},
+		{"expired", &config.Credentials{AccessToken: "a", ExpiresAt: time.Now().Add(-time.Minute)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &TokenAccessor{t: tt.token}
+			if got := a.valid(); got != tt.want {
+				t.Errorf("valid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenRefreshesBeforeExpiry(t *testing.T) {
+	requests := 0
+	a := testAccessor(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		r.ParseForm()
+		if r.URL.Path != "/v1/token" || r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh" || r.Form.Get("client_id") != "client" {
+			t.Errorf("unexpected token request: %v %v", r.URL.Path, r.Form)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "new", "expires_in": 3600}`)
+	})
+	a.t = &config.Credentials{AccessToken: "old", RefreshToken: "refresh", ExpiresAt: time.Now().Add(RefreshBefore / 2)}
+
+	for i := 0; i < 2; i++ {
+		token, err := a.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "new" {
+			t.Errorf("Token() = %v, want new", token)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("%v token requests, want 1", requests)
+	}
+
+	// The refreshed token is written back for the CLI and other processes
+	cached, err := generator.ReadTokenCache(a.c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached.AccessToken != "new" || cached.RefreshToken != "refresh" {
+		t.Errorf("cached tokens = %v/%v, want new/refresh", cached.AccessToken, cached.RefreshToken)
+	}
+}
+
+func TestTokenKeepsFreshToken(t *testing.T) {
+	a := testAccessor(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected token request")
+	})
+	a.t = &config.Credentials{AccessToken: "fresh", RefreshToken: "refresh", ExpiresAt: time.Now().Add(time.Hour)}
+
+	token, err := a.Token()
+	if err != nil || token != "fresh" {
+		t.Errorf("Token() = %v, %v, want fresh", token, err)
+	}
+}
+
+func TestTokenWithoutRefreshToken(t *testing.T) {
+	a := testAccessor(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected token request")
+	})
+	a.t = &config.Credentials{AccessToken: "old", ExpiresAt: time.Now().Add(RefreshBefore / 2)}
+
+	_, err := a.Token()
+	if !errors.Is(err, ErrNoToken) {
+		t.Errorf("Token() error = %v, want %v", err, ErrNoToken)
+	}
+}
+
+func TestTokenConcurrentRefresh(t *testing.T) {
+	// Both accessors refresh at once, each answered only when the other has asked too.
+	// Okta's clock is ahead by more than the skew threshold, which is logged rather than printed
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		arrived.Done()
+		arrived.Wait()
+
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token": "new", "expires_in": 3600}`)
+	}
+
+	accessors := []*TokenAccessor{testAccessor(t, handler), testAccessor(t, handler)}
+
+	var wg sync.WaitGroup
+	for _, a := range accessors {
+		a.t = &config.Credentials{AccessToken: "old", RefreshToken: "refresh", ExpiresAt: time.Now()}
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(a *TokenAccessor) {
+				defer wg.Done()
+
+				token, err := a.Token()
+				if err != nil || token != "new" {
+					t.Errorf("Token() = %v, %v, want new", token, err)
+				}
+			}(a)
+		}
+	}
+	wg.Wait()
+}
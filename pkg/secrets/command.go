@@ -0,0 +1,88 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+)
+
+// maxCommandOutput bounds how much of a password command's output is read
+const maxCommandOutput = 64 * 1024
+
+// commandStore reads the secret from the output of a user-configured command,
+// such as a password manager CLI
+type commandStore struct {
+	command string
+}
+
+func (s *commandStore) Name() string {
+	return "password command"
+}
+
+func (s *commandStore) Writable() bool {
+	return false
+}
+
+func (s *commandStore) Get(key string) (string, error) {
+	if s.command == "" {
+		return "", errors.New("password-command is not configured")
+	}
+
+	return runCommand(s.command)
+}
+
+func (s *commandStore) Set(key string, value string) error {
+	return ErrReadOnly
+}
+
+func (s *commandStore) Delete(key string) error {
+	return ErrReadOnly
+}
+
+// runCommand runs a password command through the shell and returns the first line of its output.
+// The terminal is passed through so that the command can prompt, e.g. to unlock a password manager.
+// The output is never included in returned errors, so that it can't end up in logs.
+func runCommand(command string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = vault.Environ()
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = io.Copy(&stdout, io.LimitReader(pipe, maxCommandOutput))
+	if err != nil {
+		cmd.Wait()
+		return "", err
+	}
+	// Anything beyond the limit is discarded so that the command isn't blocked on a full pipe
+	io.Copy(ioutil.Discard, pipe)
+
+	err = cmd.Wait()
+	if err != nil {
+		return "", errors.New("password command failed: " + err.Error())
+	}
+
+	secret := strings.TrimRight(strings.SplitN(stdout.String(), "\n", 2)[0], "\r")
+	if secret == "" {
+		return "", ErrNotFound
+	}
+
+	return secret, nil
+}
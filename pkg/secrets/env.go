@@ -0,0 +1,40 @@
+package secrets
+
+import "os"
+
+const defaultPasswordEnv = "GSC_PASSWORD"
+
+// envStore reads the secret from an environment variable, e.g. one injected by a CI system
+type envStore struct {
+	variable string
+}
+
+func (s *envStore) Name() string {
+	return "environment"
+}
+
+func (s *envStore) Writable() bool {
+	return false
+}
+
+func (s *envStore) Get(key string) (string, error) {
+	variable := s.variable
+	if variable == "" {
+		variable = defaultPasswordEnv
+	}
+
+	secret, ok := os.LookupEnv(variable)
+	if !ok || secret == "" {
+		return "", ErrNotFound
+	}
+
+	return secret, nil
+}
+
+func (s *envStore) Set(key string, value string) error {
+	return ErrReadOnly
+}
+
+func (s *envStore) Delete(key string) error {
+	return ErrReadOnly
+}
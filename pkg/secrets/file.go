@@ -0,0 +1,42 @@
+package secrets
+
+import (
+	"errors"
+
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+)
+
+// fileStore keeps secrets in the encrypted vault, for machines without an OS keyring
+type fileStore struct {
+	vault *vault.Vault
+}
+
+func (s *fileStore) Name() string {
+	return "vault"
+}
+
+func (s *fileStore) Writable() bool {
+	return true
+}
+
+func (s *fileStore) Get(key string) (string, error) {
+	secret, err := s.vault.Get(vault.Passwords, key)
+	if errors.Is(err, vault.ErrNotFound) {
+		return "", ErrNotFound
+	}
+
+	return secret, err
+}
+
+func (s *fileStore) Set(key string, value string) error {
+	return s.vault.Set(vault.Passwords, key, value)
+}
+
+func (s *fileStore) Delete(key string) error {
+	err := s.vault.Delete(vault.Passwords, key)
+	if errors.Is(err, vault.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,40 @@
+package secrets
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keyringStore keeps secrets in the OS keyring: Keychain, Secret Service or Credential Manager
+type keyringStore struct{}
+
+func (s *keyringStore) Name() string {
+	return "keyring"
+}
+
+func (s *keyringStore) Writable() bool {
+	return true
+}
+
+func (s *keyringStore) Get(key string) (string, error) {
+	secret, err := keyring.Get(service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+
+	return secret, err
+}
+
+func (s *keyringStore) Set(key string, value string) error {
+	return keyring.Set(service, key, value)
+}
+
+func (s *keyringStore) Delete(key string) error {
+	err := keyring.Delete(service, key)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNotFound
+	}
+
+	return err
+}
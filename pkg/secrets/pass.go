@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+)
+
+// passStore keeps secrets in the GPG-encrypted `pass` password store
+type passStore struct{}
+
+func (s *passStore) Name() string {
+	return "pass store"
+}
+
+func (s *passStore) Writable() bool {
+	return true
+}
+
+func (s *passStore) Get(key string) (string, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("pass", "show", s.entry(key))
+	cmd.Env = vault.Environ()
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		if strings.Contains(stderr.String(), "is not in the password store") {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+
+	// The password is the first line, anything after it is metadata
+	return strings.SplitN(stdout.String(), "\n", 2)[0], nil
+}
+
+func (s *passStore) Set(key string, value string) error {
+	cmd := exec.Command("pass", "insert", "--multiline", "--force", s.entry(key))
+	cmd.Env = vault.Environ()
+	cmd.Stdin = strings.NewReader(value + "\n")
+
+	return cmd.Run()
+}
+
+func (s *passStore) Delete(key string) error {
+	if _, err := s.Get(key); err != nil {
+		return err
+	}
+
+	cmd := exec.Command("pass", "rm", "--force", s.entry(key))
+	cmd.Env = vault.Environ()
+
+	return cmd.Run()
+}
+
+func (s *passStore) entry(key string) string {
+	return service + "/" + key
+}
@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+)
+
+const service = "gimme-snowflake-creds"
+
+var (
+	ErrNotFound = errors.New("secret not found")
+	ErrReadOnly = errors.New("secret store is read-only")
+
+	// Backends lists the names accepted by the `secret-store` profile setting
+	Backends = []string{
+		"keyring",
+		"file",
+		"pass",
+		"env",
+		"command",
+	}
+)
+
+// SecretStore is a place where secrets, such as Okta passwords, are kept between runs
+type SecretStore interface {
+	// Name describes the store in user-facing messages
+	Name() string
+	// Writable reports whether secrets can be saved to and deleted from the store
+	Writable() bool
+	Get(key string) (string, error)
+	Set(key string, value string) error
+	Delete(key string) error
+}
+
+// New returns the secret store selected by the profile
+func New(c config.Configuration) (SecretStore, error) {
+	switch c.Profile.SecretStore {
+	case "", "keyring":
+		return &keyringStore{}, nil
+	case "file":
+		return &fileStore{vault: vault.Open(c)}, nil
+	case "pass":
+		return &passStore{}, nil
+	case "env":
+		return &envStore{variable: c.Profile.PasswordEnv}, nil
+	case "command":
+		return &commandStore{command: c.Profile.PasswordCommand}, nil
+	}
+
+	return nil, fmt.Errorf("unknown secret store: %v", c.Profile.SecretStore)
+}
+
+// PasswordStore returns the store the profile's Okta password is read from: the password
+// command when one is set, whatever the secret store, so that refresh tokens can still be
+// kept in a writable store, and otherwise the secret store itself
+func PasswordStore(c config.Configuration) (SecretStore, error) {
+	if c.Profile.PasswordCommand != "" {
+		return &commandStore{command: c.Profile.PasswordCommand}, nil
+	}
+
+	return New(c)
+}
+
+// Key namespaces a profile's secrets by Okta org, so that the same username in two orgs
+// doesn't share an entry
+func Key(c config.Configuration) string {
+	u, err := url.Parse(c.Profile.OktaOrg)
+	if err != nil || u.Host == "" {
+		return c.Profile.Username
+	}
+
+	return u.Host + "/" + c.Profile.Username
+}
+
+// Migrate moves secrets saved by earlier releases under the bare username rather than the
+// profile's namespaced key
+func Migrate(s SecretStore, c config.Configuration) error {
+	key := Key(c)
+	if !s.Writable() || key == c.Profile.Username {
+		return nil
+	}
+
+	if _, err := s.Get(key); err == nil {
+		return nil
+	}
+
+	secret, err := s.Get(c.Profile.Username)
+	if err != nil {
+		return nil
+	}
+
+	err = s.Set(key, secret)
+	if err != nil {
+		return err
+	}
+
+	return s.Delete(c.Profile.Username)
+}
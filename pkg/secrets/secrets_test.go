@@ -0,0 +1,260 @@
+package secrets
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/HGInsights/gimme-snowflake-creds/pkg/vault"
+	"github.com/hashicorp/go-hclog"
+)
+
+// setenv sets an environment variable for the rest of a test, unsetting it when empty
+func setenv(t *testing.T, key string, value string) {
+	old, ok := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+}
+
+// testConfig returns a profile using the given secret store, with a vault in a temporary
+// home directory that's unlocked by passphrase
+func testConfig(t *testing.T, store string) config.Configuration {
+	setenv(t, vault.PassphraseEnv, "correct horse")
+	setenv(t, vault.KeyEnv, "")
+
+	return config.Configuration{
+		ProfileName: "dev",
+		HomeDir:     t.TempDir(),
+		Logger:      hclog.NewNullLogger(),
+		Profile: config.Profile{
+			OktaOrg:     "https://example.okta.com",
+			Username:    "gimme-user@example.com",
+			SecretStore: store,
+		},
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		store    string
+		name     string
+		writable bool
+	}{
+		{store: "", name: "keyring", writable: true},
+		{store: "keyring", name: "keyring", writable: true},
+		{store: "file", name: "vault", writable: true},
+		{store: "pass", name: "pass store", writable: true},
+		{store: "env", name: "environment", writable: false},
+		{store: "command", name: "password command", writable: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.store, func(t *testing.T) {
+			s, err := New(testConfig(t, tt.store))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			if s.Name() != tt.name || s.Writable() != tt.writable {
+				t.Errorf("New() = %v (writable %v), want %v (writable %v)", s.Name(), s.Writable(), tt.name, tt.writable)
+			}
+		})
+	}
+
+	if _, err := New(testConfig(t, "clipboard")); err == nil {
+		t.Errorf("New() of an unknown store error = nil")
+	}
+}
+
+func TestPasswordStore(t *testing.T) {
+	// A password command is used whatever the secret store, which still keeps the tokens
+	c := testConfig(t, "file")
+	c.Profile.PasswordCommand = "echo hunter2"
+
+	s, err := PasswordStore(c)
+	if err != nil || s.Name() != "password command" {
+		t.Fatalf("PasswordStore() = %v, %v, want the password command", s, err)
+	}
+	if got, err := s.Get(Key(c)); err != nil || got != "hunter2" {
+		t.Errorf("Get() = %q, %v, want %q", got, err, "hunter2")
+	}
+
+	if s, err := New(c); err != nil || s.Name() != "vault" {
+		t.Errorf("New() = %v, %v, want the vault", s, err)
+	}
+
+	c.Profile.PasswordCommand = ""
+	if s, err := PasswordStore(c); err != nil || s.Name() != "vault" {
+		t.Errorf("PasswordStore() without a command = %v, %v, want the vault", s, err)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		org  string
+		want string
+	}{
+		{org: "https://example.okta.com", want: "example.okta.com/gimme-user@example.com"},
+		{org: "https://example.okta.com/", want: "example.okta.com/gimme-user@example.com"},
+		{org: "", want: "gimme-user@example.com"},
+		{org: "example.okta.com", want: "gimme-user@example.com"},
+	}
+
+	for _, tt := range tests {
+		c := config.Configuration{Profile: config.Profile{OktaOrg: tt.org, Username: "gimme-user@example.com"}}
+		if got := Key(c); got != tt.want {
+			t.Errorf("Key() with org %q = %q, want %q", tt.org, got, tt.want)
+		}
+	}
+}
+
+func TestEnvStore(t *testing.T) {
+	s := &envStore{}
+
+	setenv(t, defaultPasswordEnv, "")
+	if _, err := s.Get("key"); err != ErrNotFound {
+		t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+	}
+
+	setenv(t, defaultPasswordEnv, "hunter2")
+	if got, err := s.Get("key"); err != nil || got != "hunter2" {
+		t.Errorf("Get() = %q, %v, want %q", got, err, "hunter2")
+	}
+
+	s = &envStore{variable: "OKTA_PASSWORD"}
+	setenv(t, "OKTA_PASSWORD", "swordfish")
+	if got, err := s.Get("key"); err != nil || got != "swordfish" {
+		t.Errorf("Get() = %q, %v, want %q", got, err, "swordfish")
+	}
+
+	if err := s.Set("key", "value"); err != ErrReadOnly {
+		t.Errorf("Set() error = %v, want %v", err, ErrReadOnly)
+	}
+	if err := s.Delete("key"); err != ErrReadOnly {
+		t.Errorf("Delete() error = %v, want %v", err, ErrReadOnly)
+	}
+}
+
+func TestCommandStore(t *testing.T) {
+	setenv(t, vault.KeyEnv, "session")
+
+	tests := []struct {
+		name    string
+		command string
+		want    string
+		wantErr bool
+	}{
+		{name: "first line", command: `printf 'hunter2\nmetadata\n'`, want: "hunter2"},
+		{name: "no trailing newline", command: `printf hunter2`, want: "hunter2"},
+		{name: "vault variables hidden", command: `printf %s "${GSC_VAULT_KEY:-hidden}"`, want: "hidden"},
+		{name: "empty output", command: `true`, wantErr: true},
+		{name: "failure", command: `echo hunter2; exit 3`, wantErr: true},
+		{name: "not configured", command: ``, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&commandStore{command: tt.command}).Get("key")
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Get() = %q, %v, want %q", got, err, tt.want)
+			}
+
+			// The output never ends up in errors, which may be logged
+			if err != nil && strings.Contains(err.Error(), "hunter2") {
+				t.Errorf("Get() error = %q, includes the output", err)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	c := testConfig(t, "file")
+	s, _ := New(c)
+
+	// Passwords saved under the bare username move to the namespaced key
+	if err := s.Set(c.Profile.Username, "hunter2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := Migrate(s, c); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if got, err := s.Get(Key(c)); err != nil || got != "hunter2" {
+		t.Errorf("Get() of the namespaced key = %q, %v, want %q", got, err, "hunter2")
+	}
+	if _, err := s.Get(c.Profile.Username); err != ErrNotFound {
+		t.Errorf("Get() of the bare username error = %v, want %v", err, ErrNotFound)
+	}
+
+	// An existing namespaced password isn't overwritten
+	if err := s.Set(c.Profile.Username, "stale"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := Migrate(s, c); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	if got, _ := s.Get(Key(c)); got != "hunter2" {
+		t.Errorf("Get() of the namespaced key = %q, want %q", got, "hunter2")
+	}
+
+	// Read-only stores are left alone
+	if err := Migrate(&envStore{}, c); err != nil {
+		t.Errorf("Migrate() of a read-only store error = %v", err)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	c := testConfig(t, "file")
+
+	if !SaveTokens(c, &config.Credentials{RefreshToken: "refresh", SessionID: "sid"}) {
+		t.Fatalf("SaveTokens() = false")
+	}
+
+	loaded := &config.Credentials{}
+	LoadTokens(c, loaded)
+	if loaded.RefreshToken != "refresh" || loaded.SessionID != "sid" {
+		t.Errorf("LoadTokens() = %q, %q, want %q, %q", loaded.RefreshToken, loaded.SessionID, "refresh", "sid")
+	}
+
+	// Tokens already held aren't replaced
+	loaded = &config.Credentials{RefreshToken: "newer"}
+	LoadTokens(c, loaded)
+	if loaded.RefreshToken != "newer" {
+		t.Errorf("LoadTokens() replaced the refresh token with %q", loaded.RefreshToken)
+	}
+
+	// A token that's no longer issued is removed rather than kept
+	if !SaveTokens(c, &config.Credentials{RefreshToken: "rotated"}) {
+		t.Fatalf("SaveTokens() = false")
+	}
+	loaded = &config.Credentials{}
+	LoadTokens(c, loaded)
+	if loaded.RefreshToken != "rotated" || loaded.SessionID != "" {
+		t.Errorf("LoadTokens() = %q, %q, want %q, %q", loaded.RefreshToken, loaded.SessionID, "rotated", "")
+	}
+
+	if err := DeleteTokens(c); err != nil {
+		t.Fatalf("DeleteTokens() error = %v", err)
+	}
+	loaded = &config.Credentials{}
+	LoadTokens(c, loaded)
+	if loaded.RefreshToken != "" {
+		t.Errorf("LoadTokens() after DeleteTokens() = %q", loaded.RefreshToken)
+	}
+
+	// Read-only stores can't hold tokens, which then go to the credentials file
+	if SaveTokens(testConfig(t, "env"), &config.Credentials{RefreshToken: "refresh"}) {
+		t.Errorf("SaveTokens() to a read-only store = true")
+	}
+}
@@ -0,0 +1,81 @@
+package secrets
+
+import (
+	"errors"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+)
+
+const (
+	// RefreshToken and SessionID name the long-lived tokens kept in a profile's secret store
+	RefreshToken = "refresh-token"
+	SessionID    = "okta-session"
+)
+
+// TokenKey is the key a profile's token of the given kind is kept under
+func TokenKey(c config.Configuration, kind string) string {
+	return kind + ":" + c.ProfileName
+}
+
+// SaveTokens keeps the refresh token and Okta session ID in the profile's secret store,
+// reporting whether they could be stored there rather than in plaintext
+func SaveTokens(c config.Configuration, t *config.Credentials) bool {
+	if t.RefreshToken == "" && t.SessionID == "" {
+		return true
+	}
+
+	store, err := New(c)
+	if err != nil || !store.Writable() {
+		return false
+	}
+
+	for kind, value := range map[string]string{RefreshToken: t.RefreshToken, SessionID: t.SessionID} {
+		if value == "" {
+			err = store.Delete(TokenKey(c, kind))
+			if errors.Is(err, ErrNotFound) {
+				err = nil
+			}
+		} else {
+			err = store.Set(TokenKey(c, kind), value)
+		}
+
+		if err != nil {
+			c.Logger.Debug("Unable to save token to "+store.Name(), "kind", kind, "error", err)
+			return false
+		}
+	}
+
+	return true
+}
+
+// LoadTokens fills in tokens kept in the profile's secret store
+func LoadTokens(c config.Configuration, t *config.Credentials) {
+	store, err := New(c)
+	if err != nil || !store.Writable() {
+		return
+	}
+
+	if t.RefreshToken == "" {
+		t.RefreshToken, _ = store.Get(TokenKey(c, RefreshToken))
+	}
+	if t.SessionID == "" {
+		t.SessionID, _ = store.Get(TokenKey(c, SessionID))
+	}
+}
+
+// DeleteTokens removes the profile's tokens from its secret store
+func DeleteTokens(c config.Configuration) error {
+	store, err := New(c)
+	if err != nil || !store.Writable() {
+		return err
+	}
+
+	for _, kind := range []string{RefreshToken, SessionID} {
+		err = store.Delete(TokenKey(c, kind))
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultSessionTTL is how long an unlocked vault stays unlocked unless told otherwise
+const DefaultSessionTTL = 8 * time.Hour
+
+// session is an unlocked vault on disk: the vault key sealed with a secret that only the
+// shell holding KeyEnv knows, so that neither the file nor the variable alone reveals the key,
+// and the key stops working once the session expires or its file is removed
+type session struct {
+	Expires time.Time `json:"expires"`
+	Nonce   []byte    `json:"nonce"`
+	Key     []byte    `json:"key"`
+}
+
+// Environ returns the environment without the vault's variables, for child processes
+func Environ() []string {
+	env := []string{}
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, "GSC_VAULT_") {
+			env = append(env, v)
+		}
+	}
+
+	return env
+}
+
+func (v *Vault) sessionDir() string {
+	return filepath.Join(filepath.Dir(v.path), "sessions")
+}
+
+// newSession stores the vault key in a session file that expires after ttl and returns the
+// value for KeyEnv, which is the session's ID and secret
+func (v *Vault) newSession(ttl time.Duration) (string, error) {
+	v.removeExpiredSessions()
+
+	id := make([]byte, 16)
+	secret := make([]byte, keyLength)
+	for _, b := range [][]byte{id, secret} {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+	}
+
+	gcm, err := sessionCipher(secret)
+	if err != nil {
+		return "", err
+	}
+
+	s := &session{
+		Expires: time.Now().Add(ttl),
+		Nonce:   make([]byte, gcm.NonceSize()),
+	}
+	if _, err := rand.Read(s.Nonce); err != nil {
+		return "", err
+	}
+	s.Key = gcm.Seal(nil, s.Nonce, v.key, nil)
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(v.sessionDir(), 0700)
+	if err != nil {
+		return "", err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(v.sessionDir(), hex.EncodeToString(id)), data, 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id) + "." + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// sessionKey opens the session named by a KeyEnv value, removing it once it has expired
+func (v *Vault) sessionKey(value string) ([]byte, error) {
+	invalid := errors.New("invalid " + KeyEnv + ": unlock the vault again")
+
+	parts := strings.Split(value, ".")
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+
+	// The ID names a file, so it must be nothing but hex
+	id, err := hex.DecodeString(parts[0])
+	if err != nil || len(id) != 16 {
+		return nil, invalid
+	}
+	secret, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || len(secret) != keyLength {
+		return nil, invalid
+	}
+
+	path := filepath.Join(v.sessionDir(), parts[0])
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, errExpired
+	} else if err != nil {
+		return nil, err
+	}
+
+	s := new(session)
+	err = json.Unmarshal(data, s)
+	if err != nil {
+		return nil, invalid
+	}
+
+	if time.Now().After(s.Expires) {
+		os.Remove(path)
+		return nil, errExpired
+	}
+
+	gcm, err := sessionCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Nonce) != gcm.NonceSize() {
+		return nil, invalid
+	}
+
+	key, err := gcm.Open(nil, s.Nonce, s.Key, nil)
+	if err != nil || len(key) != keyLength {
+		return nil, invalid
+	}
+
+	return key, nil
+}
+
+// Lock ends the session named by a KeyEnv value, reporting whether there was one to end
+func (v *Vault) Lock(value string) (bool, error) {
+	id := strings.Split(value, ".")[0]
+	if _, err := hex.DecodeString(id); err != nil || id == "" {
+		return false, errors.New("invalid " + KeyEnv)
+	}
+
+	err := os.Remove(filepath.Join(v.sessionDir(), id))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (v *Vault) removeExpiredSessions() {
+	files, err := ioutil.ReadDir(v.sessionDir())
+	if err != nil {
+		return
+	}
+
+	for _, f := range files {
+		path := filepath.Join(v.sessionDir(), f.Name())
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		s := new(session)
+		if json.Unmarshal(data, s) != nil || time.Now().After(s.Expires) {
+			v.logger.Debug("Removing expired vault session", "path", path)
+			os.Remove(path)
+		}
+	}
+}
+
+func sessionCipher(secret []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
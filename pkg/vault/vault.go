@@ -0,0 +1,411 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/HGInsights/gimme-snowflake-creds/internal/config"
+	"github.com/chzyer/readline"
+	"github.com/hashicorp/go-hclog"
+	"github.com/manifoldco/promptui"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// KeyEnv names an unlocked vault session for the rest of a shell session, see Unlock
+	KeyEnv = "GSC_VAULT_KEY"
+	// PassphraseEnv holds the vault passphrase, for non-interactive use
+	PassphraseEnv = "GSC_VAULT_PASSPHRASE"
+
+	Passwords = "passwords"
+	TOTPSeeds = "totp-seeds"
+
+	saltLength   = 16
+	keyLength    = 32
+	sealedPrefix = "gsc:v1:"
+)
+
+var (
+	ErrNotFound = errors.New("secret not found in vault")
+	// ErrLocked is returned when the passphrase is needed but there's no terminal to ask on
+	ErrLocked = errors.New("vault is locked: set " + KeyEnv + " or " + PassphraseEnv + " to use it without a terminal")
+
+	errExpired = errors.New("vault session expired")
+
+	// vaults are shared by path, so the passphrase is asked for at most once per run
+	vaults   = map[string]*Vault{}
+	vaultsMu sync.Mutex
+)
+
+// Vault is an encrypted file holding Okta passwords, refresh tokens and TOTP seeds
+// for machines without an OS keyring, such as containers. It's safe for concurrent use
+type Vault struct {
+	mu     sync.Mutex
+	path   string
+	key    []byte
+	logger hclog.Logger
+}
+
+// envelope is the on-disk format: the salt stays fixed so a derived key remains valid
+// across writes, while the nonce changes with every write
+type envelope struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+func Open(c config.Configuration) *Vault {
+	path := c.HomeDir + "/.gsc/vault"
+
+	vaultsMu.Lock()
+	defer vaultsMu.Unlock()
+
+	if v, ok := vaults[path]; ok {
+		return v
+	}
+
+	vaults[path] = &Vault{
+		path:   path,
+		logger: c.Logger,
+	}
+
+	return vaults[path]
+}
+
+func (v *Vault) Path() string {
+	return v.path
+}
+
+func (v *Vault) Get(kind string, key string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	secrets, _, err := v.load()
+	if err != nil {
+		return "", err
+	}
+
+	secret, ok := secrets[kind][key]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return secret, nil
+}
+
+func (v *Vault) Set(kind string, key string, value string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	secrets, salt, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	if secrets[kind] == nil {
+		secrets[kind] = map[string]string{}
+	}
+	secrets[kind][key] = value
+
+	return v.save(secrets, salt)
+}
+
+func (v *Vault) Delete(kind string, key string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	secrets, salt, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[kind][key]; !ok {
+		return ErrNotFound
+	}
+	delete(secrets[kind], key)
+
+	return v.save(secrets, salt)
+}
+
+// Unlock derives the vault key, creating the vault if needed, and starts a session that
+// expires after ttl. It returns the value for KeyEnv, so that later runs in the same shell
+// don't ask for the passphrase
+func (v *Vault) Unlock(ttl time.Duration) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	err := v.unlock()
+	if err != nil {
+		return "", err
+	}
+
+	return v.newSession(ttl)
+}
+
+func (v *Vault) unlock() error {
+	secrets, salt, err := v.load()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(v.path); os.IsNotExist(err) {
+		return v.save(secrets, salt)
+	}
+
+	return nil
+}
+
+// Encrypt seals a value with the vault key, e.g. for tokens stored outside the vault,
+// creating the vault if needed so that the key can be derived again later
+func (v *Vault) Encrypt(value string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	err := v.unlock()
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := v.cipher(nil)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	return sealedPrefix + base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+// Decrypt opens a value sealed by Encrypt, passing anything else through unchanged
+func (v *Vault) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	err = v.unlock()
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := v.cipher(nil)
+	if err != nil {
+		return "", err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("encrypted value is corrupt")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("unable to decrypt value: the vault may have been recreated")
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncrypted reports whether a value was sealed by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix)
+}
+
+func (v *Vault) load() (map[string]map[string]string, []byte, error) {
+	secrets := map[string]map[string]string{}
+
+	// A missing vault is empty, and there's no key to derive until it's first written
+	data, err := ioutil.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		v.logger.Debug("No vault, starting an empty one", "path", v.path)
+		return secrets, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	e := new(envelope)
+	err = json.Unmarshal(data, e)
+	if err != nil || len(e.Salt) != saltLength {
+		return nil, nil, errors.New("vault is corrupt")
+	}
+
+	gcm, err := v.cipher(e.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(e.Nonce) != gcm.NonceSize() {
+		return nil, nil, errors.New("vault is corrupt")
+	}
+
+	plaintext, err := gcm.Open(nil, e.Nonce, e.Ciphertext, nil)
+	if err != nil {
+		return nil, nil, errors.New("unable to decrypt vault: wrong passphrase or stale " + KeyEnv)
+	}
+
+	err = json.Unmarshal(plaintext, &secrets)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return secrets, e.Salt, nil
+}
+
+func (v *Vault) save(secrets map[string]map[string]string, salt []byte) error {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	// A new vault gets its own salt and a passphrase that's confirmed before anything is
+	// encrypted with it, rather than the key of a session that belonged to an earlier vault
+	if salt == nil {
+		salt = make([]byte, saltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+
+		v.key = nil
+		if _, err := v.deriveKey(salt, true); err != nil {
+			return err
+		}
+	}
+
+	gcm, err := v.cipher(salt)
+	if err != nil {
+		return err
+	}
+
+	e := &envelope{
+		Version: 1,
+		Salt:    salt,
+		Nonce:   make([]byte, gcm.NonceSize()),
+	}
+	if _, err := rand.Read(e.Nonce); err != nil {
+		return err
+	}
+	e.Ciphertext = gcm.Seal(nil, e.Nonce, plaintext, nil)
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(v.path), 0700)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(v.path, data, 0600)
+}
+
+func (v *Vault) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := v.deriveKey(salt, false)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// deriveKey prefers a key from an unlocked session, then a passphrase from the environment,
+// and only then prompts, never without a terminal, e.g. in a service; the key is derived once per run.
+// Creating a vault skips the session and asks for the passphrase twice
+func (v *Vault) deriveKey(salt []byte, create bool) ([]byte, error) {
+	if v.key != nil {
+		return v.key, nil
+	}
+
+	// An expired session falls back to the passphrase
+	if value := os.Getenv(KeyEnv); value != "" && !create {
+		key, err := v.sessionKey(value)
+		if err == nil {
+			v.key = key
+			return v.key, nil
+		} else if err != errExpired {
+			return nil, err
+		}
+
+		v.logger.Debug("Vault session expired, asking for the passphrase")
+	}
+
+	passphrase := os.Getenv(PassphraseEnv)
+	if passphrase == "" {
+		if !readline.IsTerminal(int(os.Stdin.Fd())) {
+			return nil, ErrLocked
+		}
+
+		label := "Passphrase for " + v.path
+		if create {
+			label = "New passphrase for " + v.path
+		}
+
+		prompt := promptui.Prompt{
+			Label: label,
+			Validate: func(input string) error {
+				if len(input) == 0 {
+					return errors.New("passphrase must not be empty")
+				}
+				return nil
+			},
+			Mask: '*',
+		}
+
+		var err error
+		passphrase, err = prompt.Run()
+		if err != nil {
+			return nil, err
+		}
+
+		if create {
+			confirm := promptui.Prompt{
+				Label: "Confirm passphrase",
+				Validate: func(input string) error {
+					if input != passphrase {
+						return errors.New("passphrases don't match")
+					}
+					return nil
+				},
+				Mask: '*',
+			}
+
+			_, err = confirm.Run()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keyLength)
+	if err != nil {
+		return nil, err
+	}
+
+	v.key = key
+	return v.key, nil
+}
@@ -0,0 +1,266 @@
+package vault
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// setenv sets an environment variable for the rest of a test, unsetting it when empty
+func setenv(t *testing.T, key string, value string) {
+	old, ok := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+
+	if value == "" {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+}
+
+// testVault returns a vault in a temporary directory, which the passphrase prompt is never
+// reached for: tests set PassphraseEnv or KeyEnv, and stdin isn't a terminal
+func testVault(t *testing.T, dir string, passphrase string) *Vault {
+	setenv(t, PassphraseEnv, passphrase)
+	setenv(t, KeyEnv, "")
+
+	return &Vault{path: filepath.Join(dir, "vault"), logger: hclog.NewNullLogger()}
+}
+
+func TestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	err := testVault(t, dir, "correct horse").Set(Passwords, "user", "hunter2")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	// A new vault derives the key again from the passphrase
+	v := testVault(t, dir, "correct horse")
+	got, err := v.Get(Passwords, "user")
+	if err != nil || got != "hunter2" {
+		t.Fatalf("Get() = %q, %v, want %q", got, err, "hunter2")
+	}
+
+	if _, err := v.Get(TOTPSeeds, "user"); err != ErrNotFound {
+		t.Errorf("Get() of another kind error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := v.Delete(Passwords, "user"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := testVault(t, dir, "correct horse").Get(Passwords, "user"); err != ErrNotFound {
+		t.Errorf("Get() after Delete() error = %v, want %v", err, ErrNotFound)
+	}
+
+	sealed, err := v.Encrypt("token")
+	if err != nil || !IsEncrypted(sealed) {
+		t.Fatalf("Encrypt() = %q, %v", sealed, err)
+	}
+	if got, err := testVault(t, dir, "correct horse").Decrypt(sealed); err != nil || got != "token" {
+		t.Errorf("Decrypt() = %q, %v, want %q", got, err, "token")
+	}
+}
+
+func TestMissingVault(t *testing.T) {
+	// Without a passphrase, anything that derived a key would fail with ErrLocked
+	v := testVault(t, t.TempDir(), "")
+
+	if _, err := v.Get(Passwords, "user"); err != ErrNotFound {
+		t.Errorf("Get() error = %v, want %v", err, ErrNotFound)
+	}
+	if err := v.Delete(Passwords, "user"); err != ErrNotFound {
+		t.Errorf("Delete() error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := os.Stat(v.path); !os.IsNotExist(err) {
+		t.Errorf("vault was created: %v", err)
+	}
+
+	if err := v.Set(Passwords, "user", "hunter2"); err != ErrLocked {
+		t.Errorf("Set() error = %v, want %v", err, ErrLocked)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+
+	err := testVault(t, dir, "correct horse").Set(Passwords, "user", "hunter2")
+	if err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	_, err = testVault(t, dir, "battery staple").Get(Passwords, "user")
+	if err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Get() error = %v, want a wrong passphrase error", err)
+	}
+}
+
+func TestCorruptEnvelope(t *testing.T) {
+	dir := t.TempDir()
+	v := testVault(t, dir, "correct horse")
+	if err := v.Set(Passwords, "user", "hunter2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	data, err := ioutil.ReadFile(v.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := envelope{}
+	if err := json.Unmarshal(data, &valid); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(e *envelope)
+		raw    string
+		want   string
+	}{
+		{name: "not JSON", raw: "not json", want: "vault is corrupt"},
+		{name: "short salt", modify: func(e *envelope) { e.Salt = e.Salt[:4] }, want: "vault is corrupt"},
+		{name: "short nonce", modify: func(e *envelope) { e.Nonce = e.Nonce[:4] }, want: "vault is corrupt"},
+		{name: "tampered ciphertext", modify: func(e *envelope) { e.Ciphertext[0] ^= 0xff }, want: "unable to decrypt vault"},
+		{name: "other salt", modify: func(e *envelope) { e.Salt[0] ^= 0xff }, want: "unable to decrypt vault"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []byte(tt.raw)
+			if tt.modify != nil {
+				e := valid
+				e.Salt = append([]byte{}, valid.Salt...)
+				e.Nonce = append([]byte{}, valid.Nonce...)
+				e.Ciphertext = append([]byte{}, valid.Ciphertext...)
+				tt.modify(&e)
+
+				data, _ = json.Marshal(e)
+			}
+			if err := ioutil.WriteFile(v.path, data, 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := testVault(t, dir, "correct horse").Get(Passwords, "user")
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Get() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	dir := t.TempDir()
+
+	v := testVault(t, dir, "correct horse")
+	if err := v.Set(Passwords, "user", "hunter2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	session, err := v.Unlock(time.Hour)
+	if err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	// The variable names the session, and doesn't hold the key
+	if strings.Contains(session, string(v.key)) || len(strings.Split(session, ".")) != 2 {
+		t.Errorf("Unlock() = %q, want <id>.<secret>", session)
+	}
+
+	v = testVault(t, dir, "")
+	setenv(t, KeyEnv, session)
+	if got, err := v.Get(Passwords, "user"); err != nil || got != "hunter2" {
+		t.Fatalf("Get() with %v = %q, %v, want %q", KeyEnv, got, err, "hunter2")
+	}
+
+	// A secret that doesn't belong to the session is rejected, as is a malformed variable,
+	// including a key in the format unlock used to export
+	other := strings.Split(session, ".")[0] + "." + strings.Repeat("A", 43)
+	for _, value := range []string{other, "bad", "../vault.AAAA", strings.Repeat("A", 43) + "="} {
+		v = testVault(t, dir, "")
+		setenv(t, KeyEnv, value)
+		if _, err := v.Get(Passwords, "user"); err == nil || !strings.HasPrefix(err.Error(), "invalid "+KeyEnv) {
+			t.Errorf("Get() with %v=%q error = %v, want invalid %v", KeyEnv, value, err, KeyEnv)
+		}
+	}
+
+	// Once locked, the session falls back to the passphrase
+	if ok, err := v.Lock(session); !ok || err != nil {
+		t.Fatalf("Lock() = %v, %v", ok, err)
+	}
+	v = testVault(t, dir, "")
+	setenv(t, KeyEnv, session)
+	if _, err := v.Get(Passwords, "user"); err != ErrLocked {
+		t.Errorf("Get() after Lock() error = %v, want %v", err, ErrLocked)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	dir := t.TempDir()
+
+	v := testVault(t, dir, "correct horse")
+	if err := v.Set(Passwords, "user", "hunter2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	session, err := v.Unlock(-time.Second)
+	if err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	// An expired session is removed, and the passphrase is used instead
+	v = testVault(t, dir, "correct horse")
+	setenv(t, KeyEnv, session)
+	if got, err := v.Get(Passwords, "user"); err != nil || got != "hunter2" {
+		t.Fatalf("Get() = %q, %v, want %q", got, err, "hunter2")
+	}
+
+	files, _ := ioutil.ReadDir(filepath.Join(dir, "sessions"))
+	if len(files) != 0 {
+		t.Errorf("expired session wasn't removed: %v", files[0].Name())
+	}
+}
+
+func TestNewVaultIgnoresSession(t *testing.T) {
+	dir := t.TempDir()
+
+	v := testVault(t, dir, "correct horse")
+	if err := v.Set(Passwords, "user", "hunter2"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	session, err := v.Unlock(time.Hour)
+	if err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+
+	// A session for a removed vault isn't used to create the next one
+	os.Remove(v.path)
+	v = testVault(t, dir, "")
+	setenv(t, KeyEnv, session)
+	if err := v.Set(Passwords, "user", "hunter2"); err != ErrLocked {
+		t.Errorf("Set() error = %v, want %v", err, ErrLocked)
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	setenv(t, KeyEnv, "session")
+	setenv(t, PassphraseEnv, "correct horse")
+	setenv(t, "GSC_LOG", "DEBUG")
+
+	env := strings.Join(Environ(), "\n")
+	if strings.Contains(env, KeyEnv) || strings.Contains(env, PassphraseEnv) {
+		t.Errorf("Environ() kept the vault variables")
+	}
+	if !strings.Contains(env, "GSC_LOG=DEBUG") {
+		t.Errorf("Environ() dropped GSC_LOG")
+	}
+}
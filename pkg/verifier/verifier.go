@@ -1,12 +1,10 @@
 package verifier
 
 import (
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
-	"math/rand"
-	"strings"
-	"time"
 )
 
 const (
@@ -28,11 +26,11 @@ func CreateCodeVerifierWithLength(length int) (*CodeVerifier, error) {
 		return nil, fmt.Errorf("invalid length: %v", length)
 	}
 
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	b := make([]byte, length)
 
-	for i := 0; i < length; i++ {
-		b[i] = byte(r.Intn(255))
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
 	}
 
 	return CreateCodeVerifierFromBytes(b)
@@ -52,6 +50,8 @@ func (v *CodeVerifier) CodeChallengePlain() string {
 	return v.Value
 }
 
+// CodeChallengeS256 derives the S256 code challenge, which for the RFC 7636 appendix B
+// verifier "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk" is "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
 func (v *CodeVerifier) CodeChallengeS256() string {
 	h := sha256.New()
 	h.Write([]byte(v.Value))
@@ -59,11 +59,7 @@ func (v *CodeVerifier) CodeChallengeS256() string {
 	return encode(h.Sum(nil))
 }
 
+// encode applies the unpadded base64url encoding required by RFC 7636
 func encode(msg []byte) string {
-	encoded := base64.StdEncoding.EncodeToString(msg)
-	encoded = strings.Replace(encoded, "+", "-", -1)
-	encoded = strings.Replace(encoded, "/", "_", -1)
-	encoded = strings.Replace(encoded, "=", "", -1)
-
-	return encoded
+	return base64.RawURLEncoding.EncodeToString(msg)
 }
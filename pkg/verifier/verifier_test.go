@@ -0,0 +1,69 @@
+package verifier
+
+import (
+	"regexp"
+	"testing"
+)
+
+// unreserved is the character set allowed in a code verifier by RFC 7636 section 4.1
+var unreserved = regexp.MustCompile(`^[A-Za-z0-9\-._~]+$`)
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 appendix B
+	v := &CodeVerifier{Value: "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"}
+
+	want := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	if got := v.CodeChallengeS256(); got != want {
+		t.Errorf("CodeChallengeS256() = %v, want %v", got, want)
+	}
+}
+
+func TestCreateCodeVerifierFromBytes(t *testing.T) {
+	// RFC 7636 appendix B
+	b := []byte{
+		116, 24, 223, 180, 151, 153, 224, 37, 79, 250, 96, 125, 216, 173,
+		187, 186, 22, 212, 37, 77, 105, 214, 191, 240, 91, 88, 5, 88, 83,
+		132, 141, 121,
+	}
+
+	v, err := CreateCodeVerifierFromBytes(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	if v.Value != want {
+		t.Errorf("Value = %v, want %v", v.Value, want)
+	}
+}
+
+func TestCreateCodeVerifier(t *testing.T) {
+	for _, length := range []int{MinLength, DefaultLength, MaxLength} {
+		v, err := CreateCodeVerifierWithLength(length)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// RFC 7636 section 4.1 requires 43 to 128 characters
+		if len(v.Value) < 43 || len(v.Value) > 128 {
+			t.Errorf("length %v: verifier has %v characters, want 43 to 128", length, len(v.Value))
+		}
+		if !unreserved.MatchString(v.Value) {
+			t.Errorf("length %v: verifier %v has characters outside the unreserved set", length, v.Value)
+		}
+	}
+
+	v, err := CreateCodeVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Value) != 43 {
+		t.Errorf("default verifier has %v characters, want 43", len(v.Value))
+	}
+
+	for _, length := range []int{MinLength - 1, MaxLength + 1} {
+		if _, err := CreateCodeVerifierWithLength(length); err == nil {
+			t.Errorf("length %v: expected an error", length)
+		}
+	}
+}